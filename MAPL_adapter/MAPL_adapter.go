@@ -38,6 +38,7 @@ type (
 		listener net.Listener
 		server   *grpc.Server
 		rules MAPL_engine.Rules
+		policy *MAPL_engine.Policy // the compiled rules
 	}
 )
 
@@ -58,7 +59,11 @@ func (s *MaplAdapter) HandleAuthorization(ctx context.Context, authRequest *auth
 	}
 
 	message := convertAuthRequestToMaplMessage(authRequest)  // convert authRequest (from the mixer) to message attributes as in the definitions.go file.
-	maplCode, _, _, _, _:= MAPL_engine.Check(&message, &s.rules)  // check the message against the rules with the MAPL_engine's Check function.
+	maplCode, _, _, _, _, auditResults := s.policy.CheckWithAudit(&message)  // check the message against the rules with the MAPL_engine's compiled policy (same decision as the Check function).
+	for _, auditResult := range auditResults { // audit rules do not change the decision. log them to observe their impact before they are enforced.
+		log.Printf("Audit rule_id=%v: %v [%d] would have decided %v [%d] (decision %d)\n", auditResult.RuleID, auditResult.ResultString, auditResult.Result,
+			MAPL_engine.ActionTypeNames[auditResult.WouldHaveDecided], auditResult.WouldHaveDecided, maplCode)
+	}
 	statusCode,statusMsg:=convertDecisionToIstioCode(maplCode) // convert MAPL_engine's decision to Istio's status code.
 
 	//log.Println("logger",Params.Logger)
@@ -150,15 +155,21 @@ func NewMaplAdapter(port string, rulesFilename string) (Server, error) {
 	if port == "" {
 		port = "0"
 	}
+	bundle, err := MAPL_engine.LoadBundle(rulesFilename) // a rules file, or a directory or manifest of a policy bundle. bad rules are rejected without crashing the adapter
+	if err != nil {
+		return nil, fmt.Errorf("unable to read rules: %v", err)
+	}
+
 	listener, err := net.Listen("tcp", fmt.Sprintf(":%s", port))
 	if err != nil {
 		return nil, fmt.Errorf("unable to listen on socket: %v", err)
 	}
 	s := &MaplAdapter{
 		listener: listener,
-		rules: MAPL_engine.YamlReadRulesFromFile(rulesFilename),
+		rules: bundle.Rules,
 	}
-	log.Printf("read %v rules from file \"%v\"\n",len(s.rules.Rules),rulesFilename)
+	s.policy = MAPL_engine.NewPolicy(&s.rules)
+	log.Printf("read %v rules from %v files of \"%v\"\n",len(s.rules.Rules),len(bundle.Files),rulesFilename)
 	log.Printf("listening on \"%v\"\n", s.Addr())
 	s.server = grpc.NewServer()
 	authorization.RegisterHandleAuthorizationServiceServer(s.server, s)
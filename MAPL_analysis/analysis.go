@@ -0,0 +1,343 @@
+// Package MAPL_analysis contains static analysis of MAPL rules: shadowed rules, conflicting rules and duplicate rules
+package MAPL_analysis
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/octarinesec/MAPL/MAPL_engine"
+)
+
+// FindingType is the type of a finding of the analysis
+type FindingType string
+
+const (
+	// Shadowed: the rule is fully subsumed by another rule with an equal or stronger decision, so it never changes the decision
+	Shadowed FindingType = "shadowed"
+	// Conflict: the rules overlap and have conflicting decisions (the most restrictive decision wins in the overlapping region)
+	Conflict FindingType = "conflict"
+	// Duplicate: the rules are the same except for their rule ids
+	Duplicate FindingType = "duplicate"
+)
+
+// Region is the overlapping region of two rules: the sender, receiver, protocol, resource and operation matched by both rules.
+// Each part is a pattern as written in the rules. A part that cannot be narrowed is written as both patterns joined by '&'.
+type Region struct {
+	Sender       string `json:"sender" yaml:"sender"`
+	Receiver     string `json:"receiver" yaml:"receiver"`
+	Protocol     string `json:"protocol" yaml:"protocol"`
+	ResourceType string `json:"resourceType" yaml:"resourceType"`
+	ResourceName string `json:"resourceName" yaml:"resourceName"`
+	Operation    string `json:"operation" yaml:"operation"`
+}
+
+// Finding is one result of the analysis.
+// Shadowed: RuleIDs are the shadowed rule and the rule that shadows it.
+// Conflict: RuleIDs are the two conflicting rules.
+// Duplicate: RuleIDs are all of the rules with the same hash (the first rule in the list is the first in the rules).
+type Finding struct {
+	Type        FindingType `json:"type" yaml:"type"`
+	RuleIDs     []string    `json:"ruleIds" yaml:"ruleIds"`
+	RuleIndices []int       `json:"ruleIndices" yaml:"ruleIndices"`
+	Region      Region      `json:"region" yaml:"region"`
+	Conditional bool        `json:"conditional,omitempty" yaml:"conditional,omitempty"` // Conflict: at least one of the rules has conditions so the rules may not apply to the same messages
+	Description string      `json:"description" yaml:"description"`
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%v %v: %v", f.Type, strings.Join(f.RuleIDs, ","), f.Description)
+}
+
+// decision strength by order of precedence (as in MAPL_engine.Check)
+var decisionStrength = map[string]int{
+	"allow": MAPL_engine.ALLOW,
+	"alert": MAPL_engine.ALERT,
+	"block": MAPL_engine.BLOCK,
+}
+
+// ruleSpace is the part of a rule used by the analysis
+type ruleSpace struct {
+	index      int
+	rule       *MAPL_engine.Rule
+	sender     endpoint
+	receiver   endpoint
+	protocol   []string
+	resType    []string
+	resName    []string
+	operation  []string
+	decision   int
+	conditions [][]string // canonical conditions of each AND clause
+	hash       string
+}
+
+// Analyze reports the shadowed, conflicting and duplicate rules.
+// The findings are sorted by type (duplicates, shadowed rules and then conflicts) and by the indices of the rules.
+// Rules in audit mode are compared only with other audit rules.
+func Analyze(rules *MAPL_engine.Rules) []Finding {
+	spaces := make([]ruleSpace, len(rules.Rules))
+	for i := range rules.Rules {
+		spaces[i] = newRuleSpace(i, &rules.Rules[i])
+	}
+
+	findings := findDuplicates(spaces)
+	duplicates := findings
+
+	conflicts := []Finding{}
+	for i := range spaces {
+		for j := i + 1; j < len(spaces); j++ {
+			if sameDuplicateGroup(duplicates, i, j) {
+				continue
+			}
+			a, b := &spaces[i], &spaces[j]
+			if MAPL_engine.IsAuditRule(a.rule) != MAPL_engine.IsAuditRule(b.rule) { // audit rules do not change the decisions of enforced rules
+				continue
+			}
+			region, overlap := a.overlap(b)
+			if !overlap {
+				continue
+			}
+			switch {
+			case a.covers(b) && a.decision >= b.decision: // the later rule is reported when both cover each other with the same decision
+				findings = append(findings, shadowedFinding(b, a, region))
+			case b.covers(a) && b.decision >= a.decision:
+				findings = append(findings, shadowedFinding(a, b, region))
+			case a.decision != b.decision:
+				conflicts = append(conflicts, conflictFinding(a, b, region))
+			}
+		}
+	}
+	return append(findings, conflicts...)
+}
+
+func newRuleSpace(index int, rule *MAPL_engine.Rule) ruleSpace {
+	s := ruleSpace{
+		index:    index,
+		rule:     rule,
+		sender:   newEndpoint(rule.Sender.SenderName, rule.Sender.SenderType),
+		receiver: newEndpoint(rule.Receiver.ReceiverName, rule.Receiver.ReceiverType),
+		protocol: splitPattern(strings.ToLower(rule.Protocol), ";"),
+		decision: decisionStrength[strings.ToLower(rule.Decision)],
+		hash:     canonicalHash(*rule),
+	}
+
+	// as in MAPL_engine: an empty resource of a rule with protocol "*" is any resource
+	resourceType, resourceName := rule.Resource.ResourceType, rule.Resource.ResourceName
+	if rule.Protocol == "*" {
+		if resourceType == "" {
+			resourceType = "*"
+		}
+		if resourceName == "" {
+			resourceName = "*"
+		}
+	}
+	s.resType = splitPattern(resourceType, ";")
+	s.resName = splitPattern(resourceName, ";")
+
+	switch rule.Operation { // as in MAPL_engine.ConvertOperationStringToRegex
+	case "write", "WRITE":
+		s.operation = []string{"POST", "PUT", "DELETE", "PRODUCE"}
+	case "read", "READ":
+		s.operation = []string{"GET", "HEAD", "OPTIONS", "TRACE", "CONSUME", "read", "READ"}
+	default:
+		s.operation = splitPattern(rule.Operation, ";")
+	}
+
+	for _, andConditions := range rule.DNFConditions {
+		clause := []string{}
+		for _, c := range andConditions.ANDConditions {
+			clause = append(clause, conditionKey(c))
+		}
+		s.conditions = append(s.conditions, clause)
+	}
+	return s
+}
+
+// conditionKey is the canonical form of a condition (as in MAPL_engine.RuleMD5Hash)
+func conditionKey(c MAPL_engine.Condition) string {
+	attribute := c.OriginalAttribute
+	if attribute == "" {
+		attribute = c.Attribute
+	}
+	value := c.OriginalValue
+	if value == "" {
+		value = c.Value
+	}
+	return "<" + attribute + ":" + strings.ToUpper(c.Method) + ":" + value + ">"
+}
+
+// covers tests that every message that the rule s2 applies to is also a message that the rule s applies to
+func (s *ruleSpace) covers(s2 *ruleSpace) bool {
+	return s.sender.covers(s2.sender) && s.receiver.covers(s2.receiver) &&
+		listCovers(s.protocol, s2.protocol) && listCovers(s.resType, s2.resType) &&
+		listCovers(s.resName, s2.resName) && listCovers(s.operation, s2.operation) &&
+		conditionsImply(s2.conditions, s.conditions) && validityCovers(s.rule, s2.rule)
+}
+
+// validityCovers tests that the validity period of rule1 contains the validity period of rule2 (a zero time is an open end)
+func validityCovers(rule1, rule2 *MAPL_engine.Rule) bool {
+	fromCovers := rule1.ValidFromTime.IsZero() || (!rule2.ValidFromTime.IsZero() && !rule2.ValidFromTime.Before(rule1.ValidFromTime))
+	untilCovers := rule1.ValidUntilTime.IsZero() || (!rule2.ValidUntilTime.IsZero() && !rule2.ValidUntilTime.After(rule1.ValidUntilTime))
+	return fromCovers && untilCovers
+}
+
+// validityOverlap tests that the validity periods of the rules have a common time
+func validityOverlap(rule1, rule2 *MAPL_engine.Rule) bool {
+	before := func(from, until time.Time) bool { // from is before until (zero times are open ends)
+		return from.IsZero() || until.IsZero() || from.Before(until)
+	}
+	return before(rule1.ValidFromTime, rule2.ValidUntilTime) && before(rule2.ValidFromTime, rule1.ValidUntilTime)
+}
+
+// overlap returns the region of the messages of both rules (regardless of the conditions) and false if there are no such messages
+// (or if the validity periods of the rules do not overlap)
+func (s *ruleSpace) overlap(s2 *ruleSpace) (Region, bool) {
+	var region Region
+	var ok bool
+	if !validityOverlap(s.rule, s2.rule) {
+		return region, false
+	}
+	if region.Sender, ok = s.sender.overlap(s2.sender); !ok {
+		return region, false
+	}
+	if region.Receiver, ok = s.receiver.overlap(s2.receiver); !ok {
+		return region, false
+	}
+	parts := []struct {
+		list1, list2 []string
+		out          *string
+	}{
+		{s.protocol, s2.protocol, &region.Protocol},
+		{s.resType, s2.resType, &region.ResourceType},
+		{s.resName, s2.resName, &region.ResourceName},
+		{s.operation, s2.operation, &region.Operation},
+	}
+	for _, part := range parts {
+		overlap := listOverlap(part.list1, part.list2)
+		if len(overlap) == 0 {
+			return region, false
+		}
+		*part.out = strings.Join(overlap, ";")
+	}
+	return region, true
+}
+
+// conditionsImply tests that the conditions c1 imply the conditions c2: each AND clause of c1 contains all of the conditions of an AND clause of c2.
+// A rule without conditions always applies.
+func conditionsImply(c1, c2 [][]string) bool {
+	if len(c2) == 0 {
+		return true
+	}
+	if len(c1) == 0 {
+		c1 = [][]string{{}}
+	}
+	for _, clause1 := range c1 {
+		implied := false
+		for _, clause2 := range c2 {
+			if isSubset(clause2, clause1) {
+				implied = true
+				break
+			}
+		}
+		if !implied {
+			return false
+		}
+	}
+	return true
+}
+
+func isSubset(list1, list2 []string) bool {
+	for _, a := range list1 {
+		found := false
+		for _, b := range list2 {
+			if a == b {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// canonicalHash is the MAPL_engine.RuleMD5Hash of the rule after sorting the lists in the sender and receiver names
+func canonicalHash(rule MAPL_engine.Rule) string {
+	rule.Sender.SenderName = sortList(rule.Sender.SenderName)
+	rule.Receiver.ReceiverName = sortList(rule.Receiver.ReceiverName)
+	return MAPL_engine.RuleMD5Hash(rule)
+}
+
+func sortList(str string) string {
+	list := strings.Split(strings.Replace(str, " ", "", -1), ";")
+	sort.Strings(list)
+	return strings.Join(list, ";")
+}
+
+func findDuplicates(spaces []ruleSpace) []Finding {
+	groups := map[string][]int{}
+	hashes := []string{}
+	for i, s := range spaces {
+		if _, ok := groups[s.hash]; !ok {
+			hashes = append(hashes, s.hash)
+		}
+		groups[s.hash] = append(groups[s.hash], i)
+	}
+
+	findings := []Finding{}
+	for _, hash := range hashes {
+		indices := groups[hash]
+		if len(indices) < 2 {
+			continue
+		}
+		f := Finding{Type: Duplicate, RuleIndices: indices}
+		for _, i := range indices {
+			f.RuleIDs = append(f.RuleIDs, spaces[i].rule.RuleID)
+		}
+		f.Region, _ = spaces[indices[0]].overlap(&spaces[indices[0]])
+		f.Description = fmt.Sprintf("rules %v are the same", strings.Join(f.RuleIDs, ","))
+		findings = append(findings, f)
+	}
+	return findings
+}
+
+func sameDuplicateGroup(duplicates []Finding, i, j int) bool {
+	for _, f := range duplicates {
+		if containsIndex(f.RuleIndices, i) && containsIndex(f.RuleIndices, j) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsIndex(list []int, i int) bool {
+	for _, v := range list {
+		if v == i {
+			return true
+		}
+	}
+	return false
+}
+
+func shadowedFinding(shadowed, by *ruleSpace, region Region) Finding {
+	return Finding{
+		Type:        Shadowed,
+		RuleIDs:     []string{shadowed.rule.RuleID, by.rule.RuleID},
+		RuleIndices: []int{shadowed.index, by.index},
+		Region:      region,
+		Description: fmt.Sprintf("rule %v (%v) is shadowed by rule %v (%v)", shadowed.rule.RuleID, shadowed.rule.Decision, by.rule.RuleID, by.rule.Decision),
+	}
+}
+
+func conflictFinding(a, b *ruleSpace, region Region) Finding {
+	return Finding{
+		Type:        Conflict,
+		RuleIDs:     []string{a.rule.RuleID, b.rule.RuleID},
+		RuleIndices: []int{a.index, b.index},
+		Region:      region,
+		Conditional: len(a.conditions) > 0 || len(b.conditions) > 0,
+		Description: fmt.Sprintf("rule %v (%v) and rule %v (%v) overlap", a.rule.RuleID, a.rule.Decision, b.rule.RuleID, b.rule.Decision),
+	}
+}
@@ -0,0 +1,103 @@
+package MAPL_analysis
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/octarinesec/MAPL/MAPL_engine"
+)
+
+// TestAnalyze tests the findings of the analysis of examples/rules_analysis.yaml
+func TestAnalyze(t *testing.T) {
+	rules := MAPL_engine.YamlReadRulesFromFile("../examples/rules_analysis.yaml")
+	findings := Analyze(&rules)
+
+	type result struct {
+		Type    FindingType
+		RuleIDs []string
+	}
+	expected := []result{
+		{Duplicate, []string{"2", "3"}},
+		{Shadowed, []string{"1", "0"}},
+		{Shadowed, []string{"6", "5"}},
+		{Conflict, []string{"2", "4"}},
+		{Conflict, []string{"3", "4"}},
+	}
+	results := []result{}
+	for _, f := range findings {
+		results = append(results, result{f.Type, f.RuleIDs})
+	}
+	if !reflect.DeepEqual(results, expected) {
+		t.Fatalf("findings: %v\nexpected: %v", findings, expected)
+	}
+
+	conflict := findings[3]
+	if !conflict.Conditional {
+		t.Errorf("conflict %v should be conditional", conflict)
+	}
+	region := Region{Sender: "service:C.my_namespace", Receiver: "service:E.my_namespace", Protocol: "tcp", ResourceType: "port", ResourceName: "80", Operation: "*"}
+	if conflict.Region != region {
+		t.Errorf("region: %+v\nexpected: %+v", conflict.Region, region)
+	}
+}
+
+func TestGlobs(t *testing.T) {
+	tests := []struct {
+		a, b             string
+		covers, overlaps bool
+	}{
+		{"*", "abc", true, true},
+		{"a*", "ab?", true, true},
+		{"ab?", "a*", false, true},
+		{"a?c", "abc", true, true},
+		{"*.ns", "a.*", false, true},
+		{"a.*", "b.*", false, false},
+		{"*a*", "*a*a*", true, true},
+		{"abc", "abd", false, false},
+	}
+	for _, test := range tests {
+		if globCovers(test.a, test.b) != test.covers {
+			t.Errorf("globCovers(%q, %q) should be %v", test.a, test.b, test.covers)
+		}
+		if globOverlaps(test.a, test.b) != test.overlaps {
+			t.Errorf("globOverlaps(%q, %q) should be %v", test.a, test.b, test.overlaps)
+		}
+	}
+}
+
+// TestAnalyzeValidity tests that rules with validity periods that do not overlap are not conflicting (examples/rules_metadata.yaml)
+// and that a rule shadows another rule only if its validity period contains the period of the other rule
+func TestAnalyzeValidity(t *testing.T) {
+	rules, err := MAPL_engine.ParseRulesFromFile("../examples/rules_metadata.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if findings := Analyze(rules); len(findings) != 0 {
+		t.Errorf("unexpected findings: %v", findings)
+	}
+
+	migration, freeze := &rules.Rules[1], &rules.Rules[2]
+	freeze.ValidFromTime, freeze.ValidUntilTime = migration.ValidFromTime, migration.ValidUntilTime.AddDate(0, 1, 0)
+	findings := Analyze(rules)
+	if len(findings) != 1 || findings[0].Type != Shadowed || !reflect.DeepEqual(findings[0].RuleIDs, []string{"migration-write", "freeze-alert"}) {
+		t.Errorf("expected migration-write to be shadowed by freeze-alert, got %v", findings)
+	}
+}
+
+// TestAnalyzeAudit tests that rules in audit mode are compared only with other audit rules (examples/rules_audit.yaml)
+func TestAnalyzeAudit(t *testing.T) {
+	rules, err := MAPL_engine.ParseRulesFromFile("../examples/rules_audit.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	findings := Analyze(rules)
+	if len(findings) != 1 || findings[0].Type != Conflict || !reflect.DeepEqual(findings[0].RuleIDs, []string{"block-legacy-payments", "alert-on-delete"}) {
+		t.Errorf("expected a conflict of the audit rules, got %v", findings)
+	}
+
+	rules.Rules[2].Mode = "" // enforced: it is compared with the enforced rules
+	findings = Analyze(rules)
+	if len(findings) != 1 || findings[0].Type != Conflict || !reflect.DeepEqual(findings[0].RuleIDs, []string{"payments-read", "block-legacy-payments"}) {
+		t.Errorf("expected a conflict of the enforced rules, got %v", findings)
+	}
+}
@@ -0,0 +1,201 @@
+package MAPL_analysis
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/octarinesec/MAPL/MAPL_engine"
+)
+
+// ChangeType is the type of a change of a rule between two lists of rules
+type ChangeType string
+
+const (
+	Added           ChangeType = "added"
+	Removed         ChangeType = "removed"
+	Renamed         ChangeType = "renamed"          // the rule id changed (the rules have the same hash)
+	DecisionChanged ChangeType = "decision-changed" // the decision changed
+	Widened         ChangeType = "widened"          // the new rule applies to all of the messages of the old rule and to more messages
+	Narrowed        ChangeType = "narrowed"         // the old rule applies to all of the messages of the new rule and to more messages
+	ScopeChanged    ChangeType = "scope-changed"    // the rules apply to different messages (neither is wider)
+	ModeChanged     ChangeType = "mode-changed"     // the rule was switched between enforce and audit mode
+)
+
+// RuleDiff is the difference of one rule between the old and the new rules.
+// The indices are of the rule in the old and the new rules (-1 for an added or removed rule).
+type RuleDiff struct {
+	Changes     []ChangeType `json:"changes"`
+	RuleID      string       `json:"ruleId"`
+	OldRuleID   string       `json:"oldRuleId,omitempty"` // used when the rule was renamed
+	OldIndex    int          `json:"oldIndex"`
+	NewIndex    int          `json:"newIndex"`
+	OldDecision string       `json:"oldDecision,omitempty"`
+	NewDecision string       `json:"newDecision,omitempty"`
+	Description string       `json:"description"`
+}
+
+// DiffReport is the semantic difference between two lists of rules
+type DiffReport struct {
+	Diffs []RuleDiff `json:"diffs"`
+}
+
+// Diff compares two lists of rules. Rules are matched by rule id, and then rules that were not matched are matched by their canonical hash
+// (the rules are the same except for the rule id). Rules that are the same (or only moved within the list) are not reported.
+// The removed rules are reported first (in the order of the old rules) and then the changed and added rules (in the order of the new rules).
+func Diff(oldRules, newRules *MAPL_engine.Rules) DiffReport {
+	oldSpaces := make([]ruleSpace, len(oldRules.Rules))
+	for i := range oldRules.Rules {
+		oldSpaces[i] = newRuleSpace(i, &oldRules.Rules[i])
+	}
+	newSpaces := make([]ruleSpace, len(newRules.Rules))
+	for i := range newRules.Rules {
+		newSpaces[i] = newRuleSpace(i, &newRules.Rules[i])
+	}
+
+	// match by rule id and then by hash
+	matches := make([]int, len(newSpaces)) // index of the old rule of each new rule
+	matched := make([]bool, len(oldSpaces))
+	oldByID := map[string]int{}
+	for i := range oldSpaces {
+		id := oldSpaces[i].rule.RuleID
+		if _, ok := oldByID[id]; !ok && id != "" {
+			oldByID[id] = i
+		}
+	}
+	for j := range newSpaces {
+		matches[j] = -1
+		if i, ok := oldByID[newSpaces[j].rule.RuleID]; ok && !matched[i] {
+			matches[j] = i
+			matched[i] = true
+		}
+	}
+	for j := range newSpaces {
+		if matches[j] >= 0 {
+			continue
+		}
+		for i := range oldSpaces {
+			if !matched[i] && oldSpaces[i].hash == newSpaces[j].hash {
+				matches[j] = i
+				matched[i] = true
+				break
+			}
+		}
+	}
+
+	report := DiffReport{Diffs: []RuleDiff{}}
+	for i := range oldSpaces {
+		if !matched[i] {
+			rule := oldSpaces[i].rule
+			report.Diffs = append(report.Diffs, RuleDiff{
+				Changes:     []ChangeType{Removed},
+				RuleID:      rule.RuleID,
+				OldIndex:    i,
+				NewIndex:    -1,
+				OldDecision: rule.Decision,
+				Description: fmt.Sprintf("rule %v (%v) was removed", rule.RuleID, rule.Decision),
+			})
+		}
+	}
+	for j := range newSpaces {
+		rule := newSpaces[j].rule
+		if matches[j] < 0 {
+			report.Diffs = append(report.Diffs, RuleDiff{
+				Changes:     []ChangeType{Added},
+				RuleID:      rule.RuleID,
+				OldIndex:    -1,
+				NewIndex:    j,
+				NewDecision: rule.Decision,
+				Description: fmt.Sprintf("rule %v (%v) was added", rule.RuleID, rule.Decision),
+			})
+			continue
+		}
+		d, changed := diffRule(&oldSpaces[matches[j]], &newSpaces[j])
+		if changed {
+			report.Diffs = append(report.Diffs, d)
+		}
+	}
+	return report
+}
+
+// diffRule compares a rule of the old rules with the matching rule of the new rules
+func diffRule(oldSpace, newSpace *ruleSpace) (RuleDiff, bool) {
+	oldRule, newRule := oldSpace.rule, newSpace.rule
+	d := RuleDiff{
+		Changes:     []ChangeType{},
+		RuleID:      newRule.RuleID,
+		OldIndex:    oldSpace.index,
+		NewIndex:    newSpace.index,
+		OldDecision: oldRule.Decision,
+		NewDecision: newRule.Decision,
+	}
+	if oldSpace.hash == newSpace.hash {
+		if oldRule.RuleID == newRule.RuleID {
+			return d, false
+		}
+		d.OldRuleID = oldRule.RuleID
+		d.Changes = append(d.Changes, Renamed)
+		d.Description = fmt.Sprintf("rule %v was renamed to %v", oldRule.RuleID, newRule.RuleID)
+		return d, true
+	}
+
+	descriptions := []string{}
+	if !strings.EqualFold(oldRule.Decision, newRule.Decision) {
+		d.Changes = append(d.Changes, DecisionChanged)
+		descriptions = append(descriptions, fmt.Sprintf("the decision changed from %v to %v", oldRule.Decision, newRule.Decision))
+	}
+	if MAPL_engine.IsAuditRule(oldRule) != MAPL_engine.IsAuditRule(newRule) {
+		d.Changes = append(d.Changes, ModeChanged)
+		descriptions = append(descriptions, fmt.Sprintf("the mode changed from %v to %v", ruleMode(oldRule), ruleMode(newRule)))
+	}
+	newCoversOld := newSpace.covers(oldSpace)
+	oldCoversNew := oldSpace.covers(newSpace)
+	switch {
+	case newCoversOld && oldCoversNew: // the same messages (for example the lists were reordered)
+	case newCoversOld:
+		d.Changes = append(d.Changes, Widened)
+		descriptions = append(descriptions, "the scope was widened")
+	case oldCoversNew:
+		d.Changes = append(d.Changes, Narrowed)
+		descriptions = append(descriptions, "the scope was narrowed")
+	default:
+		d.Changes = append(d.Changes, ScopeChanged)
+		descriptions = append(descriptions, "the scope changed")
+	}
+	if len(d.Changes) == 0 {
+		return d, false
+	}
+	d.Description = fmt.Sprintf("rule %v: %v", newRule.RuleID, strings.Join(descriptions, ", "))
+	return d, true
+}
+
+// ruleMode returns the mode of the rule (enforce for rules without a mode)
+func ruleMode(rule *MAPL_engine.Rule) string {
+	if MAPL_engine.IsAuditRule(rule) {
+		return MAPL_engine.RuleModeAudit
+	}
+	return MAPL_engine.RuleModeEnforce
+}
+
+// String returns the report as text (one line for each rule)
+func (report DiffReport) String() string {
+	var buf bytes.Buffer
+	for _, d := range report.Diffs {
+		changes := make([]string, len(d.Changes))
+		for i, c := range d.Changes {
+			changes[i] = string(c)
+		}
+		fmt.Fprintf(&buf, "%-24v %v\n", strings.Join(changes, ","), d.Description)
+	}
+	return buf.String()
+}
+
+// ToJson converts the report into a json string
+func (report DiffReport) ToJson() string {
+	jsonBytes, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		panic("error converting to json")
+	}
+	return string(jsonBytes)
+}
@@ -0,0 +1,68 @@
+package MAPL_analysis
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/octarinesec/MAPL/MAPL_engine"
+)
+
+// TestDiff tests the differences between examples/rules_analysis.yaml and a changed copy
+func TestDiff(t *testing.T) {
+	oldRules := MAPL_engine.YamlReadRulesFromFile("../examples/rules_analysis.yaml")
+	newRules := MAPL_engine.YamlReadRulesFromFile("../examples/rules_analysis.yaml")
+
+	newRules.Rules[0], newRules.Rules[1] = newRules.Rules[1], newRules.Rules[0] // moved: not reported
+	newRules.Rules[2].Sender.SenderName = "D.my_namespace;C.my_namespace"       // reordered list: not reported
+	newRules.Rules[3].RuleID = "3b"                                             // renamed
+	newRules.Rules[4].Decision = "block"                                        // decision changed
+	newRules.Rules[5].Sender.SenderName = "10.1.0.0/16"                         // narrowed
+	newRules.Rules[6].Operation = "*"                                           // widened
+	newRules.Rules[6].Decision = "alert"                                        // and decision changed
+	newRules.Rules[7].Receiver.ReceiverName = "G.my_namespace"                  // scope changed
+	newRules.Rules = append(newRules.Rules[:1], newRules.Rules[2:]...)          // rule 0 (moved to index 1) removed
+	newRules.Rules = append(newRules.Rules, MAPL_engine.Rule{RuleID: "8", Sender: newRules.Rules[0].Sender,
+		Receiver: newRules.Rules[0].Receiver, Protocol: "*", Operation: "*", Decision: "alert"}) // added
+
+	report := Diff(&oldRules, &newRules)
+
+	type result struct {
+		RuleID  string
+		Changes []ChangeType
+	}
+	expected := []result{
+		{"0", []ChangeType{Removed}},
+		{"3b", []ChangeType{Renamed}},
+		{"4", []ChangeType{DecisionChanged}},
+		{"5", []ChangeType{Narrowed}},
+		{"6", []ChangeType{DecisionChanged, Widened}},
+		{"7", []ChangeType{ScopeChanged}},
+		{"8", []ChangeType{Added}},
+	}
+	results := []result{}
+	for _, d := range report.Diffs {
+		results = append(results, result{d.RuleID, d.Changes})
+	}
+	if !reflect.DeepEqual(results, expected) {
+		t.Fatalf("diffs:\n%v\nexpected: %v", report, expected)
+	}
+	if report.Diffs[1].OldRuleID != "3" {
+		t.Errorf("the old rule id of the renamed rule should be 3: %+v", report.Diffs[1])
+	}
+
+	if len(Diff(&oldRules, &oldRules).Diffs) != 0 {
+		t.Errorf("rules should not differ from themselves")
+	}
+}
+
+// TestDiffMode tests that switching a rule between audit and enforce mode is reported
+func TestDiffMode(t *testing.T) {
+	oldRules := MAPL_engine.YamlReadRulesFromFile("../examples/rules_audit.yaml")
+	newRules := MAPL_engine.YamlReadRulesFromFile("../examples/rules_audit.yaml")
+	newRules.Rules[2].Mode = "enforce"
+
+	report := Diff(&oldRules, &newRules)
+	if len(report.Diffs) != 1 || !reflect.DeepEqual(report.Diffs[0].Changes, []ChangeType{ModeChanged}) || report.Diffs[0].RuleID != "block-legacy-payments" {
+		t.Errorf("expected the mode change of block-legacy-payments, got:\n%v", report)
+	}
+}
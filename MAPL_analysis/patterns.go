@@ -0,0 +1,347 @@
+package MAPL_analysis
+
+import (
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/octarinesec/MAPL/MAPL_engine"
+)
+
+// a pattern is a list of alternatives with wildcards (* and ?) as written in the rules (for example "A.my_namespace;B.*").
+// The analysis is conservative: covers is true only if every string matched by the inner pattern is matched by the outer pattern,
+// and overlaps is false only if no string may be matched by both patterns.
+
+// splitPattern splits a list of alternatives and removes the spaces (as in MAPL_engine.ConvertStringToRegex)
+func splitPattern(str string, separators string) []string {
+	str = strings.Replace(str, " ", "", -1)
+	list := strings.FieldsFunc(str, func(r rune) bool { return strings.ContainsRune(separators, r) })
+	if len(list) == 0 {
+		return []string{""}
+	}
+	return list
+}
+
+// listCovers tests that each alternative of the inner list is covered by one of the alternatives of the outer list
+func listCovers(outer, inner []string) bool {
+	for _, b := range inner {
+		covered := false
+		for _, a := range outer {
+			if globCovers(a, b) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return false
+		}
+	}
+	return true
+}
+
+// listOverlap returns the alternatives matched by both lists (the narrower alternative of each overlapping pair)
+func listOverlap(list1, list2 []string) []string {
+	overlap := []string{}
+	for _, a := range list1 {
+		for _, b := range list2 {
+			switch {
+			case globCovers(a, b):
+				overlap = appendUnique(overlap, b)
+			case globCovers(b, a):
+				overlap = appendUnique(overlap, a)
+			case globOverlaps(a, b):
+				overlap = appendUnique(overlap, a+"&"+b)
+			}
+		}
+	}
+	return overlap
+}
+
+func appendUnique(list []string, str string) []string {
+	for _, s := range list {
+		if s == str {
+			return list
+		}
+	}
+	return append(list, str)
+}
+
+// globCovers tests that every string matched by the pattern b is matched by the pattern a.
+// a '*' of b may only be matched by a '*' of a, and a '?' of b by a '?' or a '*' of a.
+func globCovers(a, b string) bool {
+	if a == b || a == "*" {
+		return true
+	}
+	memo := map[[2]int]bool{}
+	var covers func(i, j int) bool
+	covers = func(i, j int) bool {
+		key := [2]int{i, j}
+		if result, ok := memo[key]; ok {
+			return result
+		}
+		result := false
+		switch {
+		case i == len(a):
+			result = j == len(b)
+		case a[i] == '*':
+			result = covers(i+1, j) || (j < len(b) && covers(i, j+1))
+		case j == len(b) || b[j] == '*':
+			result = false
+		case a[i] == '?' || a[i] == b[j] && b[j] != '?':
+			result = covers(i+1, j+1)
+		}
+		memo[key] = result
+		return result
+	}
+	return covers(0, 0)
+}
+
+// globOverlaps tests if there is a string matched by both patterns
+func globOverlaps(a, b string) bool {
+	memo := map[[2]int]bool{}
+	var overlaps func(i, j int) bool
+	overlaps = func(i, j int) bool {
+		key := [2]int{i, j}
+		if result, ok := memo[key]; ok {
+			return result
+		}
+		result := false
+		switch {
+		case i == len(a) && j == len(b):
+			result = true
+		case i < len(a) && a[i] == '*':
+			result = overlaps(i+1, j) || (j < len(b) && overlaps(i, j+1))
+		case j < len(b) && b[j] == '*':
+			result = overlaps(i, j+1) || (i < len(a) && overlaps(i+1, j))
+		case i == len(a) || j == len(b):
+			result = false
+		case a[i] == '?' || b[j] == '?' || a[i] == b[j]:
+			result = overlaps(i+1, j+1)
+		}
+		memo[key] = result
+		return result
+	}
+	return overlaps(0, 0)
+}
+
+//-------------------------------------------------------------------------------------------
+// senders and receivers
+
+// endpoint is the sender or receiver of a rule
+type endpoint struct {
+	name     string
+	typ      string
+	patterns []string
+}
+
+func newEndpoint(name, typ string) endpoint {
+	if typ == "*" {
+		typ = "service"
+	}
+	e := endpoint{name: name, typ: typ}
+	switch typ {
+	case "labels":
+		e.patterns = strings.Split(name, ";")
+	default:
+		e.patterns = splitPattern(name, ";,")
+	}
+	return e
+}
+
+// isAny returns true for an endpoint that matches any message ("*" of type service)
+func (e endpoint) isAny() bool {
+	return e.typ == "service" && listCovers(e.patterns, []string{"*"})
+}
+
+func (e endpoint) String() string {
+	return e.typ + ":" + e.name
+}
+
+// covers tests that every message of the endpoint e2 is matched by the endpoint e
+func (e endpoint) covers(e2 endpoint) bool {
+	if e.isAny() {
+		return true
+	}
+	if e.typ != e2.typ {
+		return false
+	}
+	switch e.typ {
+	case "subnet":
+		return subnetListCovers(e.patterns, e2.patterns)
+	case "labels":
+		return selectorListCovers(e.patterns, e2.patterns)
+	}
+	return listCovers(e.patterns, e2.patterns)
+}
+
+// overlap returns the description of the messages matched by both endpoints (and false if there are none)
+func (e endpoint) overlap(e2 endpoint) (string, bool) {
+	if e.covers(e2) {
+		return e2.String(), true
+	}
+	if e2.covers(e) {
+		return e.String(), true
+	}
+	if e.typ != e2.typ { // different attributes of the message (for example a service and a subnet) may be of the same message
+		return e.String() + "&" + e2.String(), true
+	}
+	switch e.typ {
+	case "subnet":
+		overlap := subnetListOverlap(e.patterns, e2.patterns)
+		return e.typ + ":" + strings.Join(overlap, ";"), len(overlap) > 0
+	case "labels":
+		if !selectorListOverlaps(e.patterns, e2.patterns) {
+			return "", false
+		}
+		return e.String() + "&" + e2.String(), true
+	}
+	overlap := listOverlap(e.patterns, e2.patterns)
+	return e.typ + ":" + strings.Join(overlap, ";"), len(overlap) > 0
+}
+
+// parseSubnet returns the network of an IP or a CIDR
+func parseSubnet(str string) *net.IPNet {
+	_, ipNet, err := net.ParseCIDR(str)
+	if err == nil {
+		return ipNet
+	}
+	ip := net.ParseIP(str)
+	if ip == nil {
+		return nil
+	}
+	bits := 128
+	if ip.To4() != nil {
+		ip = ip.To4()
+		bits = 32
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+}
+
+// subnetCovers tests that the network n1 contains the network n2
+func subnetCovers(n1, n2 *net.IPNet) bool {
+	ones1, bits1 := n1.Mask.Size()
+	ones2, bits2 := n2.Mask.Size()
+	return bits1 == bits2 && ones1 <= ones2 && n1.Contains(n2.IP)
+}
+
+func subnetListCovers(outer, inner []string) bool {
+	for _, b := range inner {
+		n2 := parseSubnet(b)
+		covered := false
+		for _, a := range outer {
+			n1 := parseSubnet(a)
+			if n1 != nil && n2 != nil && subnetCovers(n1, n2) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return false
+		}
+	}
+	return true
+}
+
+// subnetListOverlap returns the networks in both lists (two networks overlap only if one contains the other)
+func subnetListOverlap(list1, list2 []string) []string {
+	overlap := []string{}
+	for _, a := range list1 {
+		for _, b := range list2 {
+			n1, n2 := parseSubnet(a), parseSubnet(b)
+			if n1 == nil || n2 == nil {
+				continue
+			}
+			if subnetCovers(n1, n2) {
+				overlap = appendUnique(overlap, n2.String())
+			} else if subnetCovers(n2, n1) {
+				overlap = appendUnique(overlap, n1.String())
+			}
+		}
+	}
+	return overlap
+}
+
+// selectorListCovers tests that each selector of the inner list has all of the requirements of one of the selectors of the outer list
+func selectorListCovers(outer, inner []string) bool {
+	for _, b := range inner {
+		s2, err := MAPL_engine.ParseLabelSelector(b)
+		if err != nil {
+			return false
+		}
+		covered := false
+		for _, a := range outer {
+			s1, err := MAPL_engine.ParseLabelSelector(a)
+			if err == nil && requirementsSubset(s1.Requirements, s2.Requirements) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return false
+		}
+	}
+	return true
+}
+
+func requirementsSubset(r1, r2 []MAPL_engine.LabelRequirement) bool {
+	keys := map[string]bool{}
+	for _, r := range r2 {
+		keys[requirementKey(r)] = true
+	}
+	for _, r := range r1 {
+		if !keys[requirementKey(r)] {
+			return false
+		}
+	}
+	return true
+}
+
+func requirementKey(r MAPL_engine.LabelRequirement) string {
+	values := append([]string{}, r.Values...)
+	sort.Strings(values)
+	return r.Key + " " + r.Operator + " " + strings.Join(values, ",")
+}
+
+// selectorListOverlaps returns false only if each pair of selectors has contradicting requirements on the same key
+func selectorListOverlaps(list1, list2 []string) bool {
+	for _, a := range list1 {
+		for _, b := range list2 {
+			s1, err1 := MAPL_engine.ParseLabelSelector(a)
+			s2, err2 := MAPL_engine.ParseLabelSelector(b)
+			if err1 != nil || err2 != nil || !requirementsContradict(s1.Requirements, s2.Requirements) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func requirementsContradict(r1, r2 []MAPL_engine.LabelRequirement) bool {
+	for _, a := range r1 {
+		for _, b := range r2 {
+			if a.Key != b.Key {
+				continue
+			}
+			aExists := a.Operator == "=" || a.Operator == "in" || a.Operator == "exists"
+			bExists := b.Operator == "=" || b.Operator == "in" || b.Operator == "exists"
+			if (aExists && b.Operator == "!") || (bExists && a.Operator == "!") {
+				return true
+			}
+			if (a.Operator == "=" || a.Operator == "in") && (b.Operator == "=" || b.Operator == "in") && !valuesIntersect(a.Values, b.Values) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func valuesIntersect(list1, list2 []string) bool {
+	for _, a := range list1 {
+		for _, b := range list2 {
+			if a == b {
+				return true
+			}
+		}
+	}
+	return false
+}
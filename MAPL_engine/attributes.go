@@ -0,0 +1,534 @@
+package MAPL_engine
+
+//go:generate go run ../cmd/gen_attributes_doc -o ../docs/SUPPORTED_ATTRIBUTES.md
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AttributeType is the type of the value extracted from the message for a condition keyword
+type AttributeType int
+
+const (
+	StringAttribute AttributeType = iota
+	IntAttribute
+	FloatAttribute
+	DurationAttribute
+	IPAttribute
+	TimeAttribute
+)
+
+var AttributeTypeNames = [...]string{
+	StringAttribute:   "string",
+	IntAttribute:      "int",
+	FloatAttribute:    "float",
+	DurationAttribute: "duration",
+	IPAttribute:       "IP",
+	TimeAttribute:     "time",
+}
+
+// Attribute maps a condition keyword to a typed extractor of the value from the message attributes.
+// Exactly one of the extractors (according to the Type) must be set. The extractor returns the value and whether the attribute
+// exists in the message (see the EX and NEX methods).
+type Attribute struct {
+	Keyword     string // the condition keyword used in the rules
+	Type        AttributeType
+	Field       string // the message attribute the value is extracted from (used in the documentation)
+	Description string
+
+	String   func(message *MessageAttributes) (string, bool)
+	Int      func(message *MessageAttributes) (int64, bool)
+	Float    func(message *MessageAttributes) (float64, bool)
+	Duration func(message *MessageAttributes) (time.Duration, bool)
+	IP       func(message *MessageAttributes) (net.IP, bool)
+	Time     func(message *MessageAttributes) (time.Time, bool)
+}
+
+var (
+	ErrInvalidAttribute    = errors.New("invalid attribute")
+	ErrAttributeRegistered = errors.New("condition keyword is already registered")
+)
+
+// keywords that are handled by the engine and cannot be registered
+var reservedKeywords = []string{"true", "TRUE", "false", "FALSE", "senderLabel", "receiverLabel", "senderLabels", "receiverLabels"}
+
+var attributeRegistry = struct {
+	sync.RWMutex
+	attributes map[string]*Attribute
+}{attributes: make(map[string]*Attribute)}
+
+// RegisterAttribute adds a condition keyword to the attribute registry. It may be called from outside the package
+// (before the rules that use the keyword are read).
+func RegisterAttribute(attribute Attribute) error {
+	if attribute.Keyword == "" || strings.ContainsAny(attribute.Keyword, "[]: ") {
+		return fmt.Errorf("%w: keyword %q", ErrInvalidAttribute, attribute.Keyword)
+	}
+	for _, keyword := range reservedKeywords {
+		if attribute.Keyword == keyword {
+			return fmt.Errorf("%w: %q", ErrAttributeRegistered, attribute.Keyword)
+		}
+	}
+
+	extractors := 0
+	for _, set := range []bool{attribute.String != nil, attribute.Int != nil, attribute.Float != nil, attribute.Duration != nil, attribute.IP != nil, attribute.Time != nil} {
+		if set {
+			extractors++
+		}
+	}
+	if extractors != 1 || attribute.extractorIsMissing() {
+		return fmt.Errorf("%w: keyword %q must have exactly one extractor of type %v", ErrInvalidAttribute, attribute.Keyword, attribute.Type)
+	}
+
+	attributeRegistry.Lock()
+	defer attributeRegistry.Unlock()
+	if _, ok := attributeRegistry.attributes[attribute.Keyword]; ok {
+		return fmt.Errorf("%w: %q", ErrAttributeRegistered, attribute.Keyword)
+	}
+	attributeRegistry.attributes[attribute.Keyword] = &attribute
+	return nil
+}
+
+// LookupAttribute returns the registered attribute of the condition keyword
+func LookupAttribute(keyword string) (Attribute, bool) {
+	attribute := lookupAttribute(keyword)
+	if attribute == nil {
+		return Attribute{}, false
+	}
+	return *attribute, true
+}
+
+// Attributes returns all of the registered attributes sorted by keyword
+func Attributes() []Attribute {
+	attributeRegistry.RLock()
+	defer attributeRegistry.RUnlock()
+	attributes := make([]Attribute, 0, len(attributeRegistry.attributes))
+	for _, attribute := range attributeRegistry.attributes {
+		attributes = append(attributes, *attribute)
+	}
+	sort.Slice(attributes, func(i, j int) bool { return attributes[i].Keyword < attributes[j].Keyword })
+	return attributes
+}
+
+func lookupAttribute(keyword string) *Attribute {
+	attributeRegistry.RLock()
+	defer attributeRegistry.RUnlock()
+	return attributeRegistry.attributes[keyword]
+}
+
+// String returns the name of the attribute type
+func (t AttributeType) String() string {
+	if t < 0 || int(t) >= len(AttributeTypeNames) {
+		return "unknown"
+	}
+	return AttributeTypeNames[t]
+}
+
+func (a *Attribute) extractorIsMissing() bool {
+	switch a.Type {
+	case StringAttribute:
+		return a.String == nil
+	case IntAttribute:
+		return a.Int == nil
+	case FloatAttribute:
+		return a.Float == nil
+	case DurationAttribute:
+		return a.Duration == nil
+	case IPAttribute:
+		return a.IP == nil
+	case TimeAttribute:
+		return a.Time == nil
+	}
+	return true
+}
+
+// Methods returns the methods supported for the attribute's type
+func (a *Attribute) Methods() []string {
+	switch a.Type {
+	case StringAttribute:
+		return stringMethods
+	case IntAttribute, FloatAttribute, DurationAttribute:
+		return numericMethods
+	case IPAttribute:
+		return ipMethods
+	case TimeAttribute:
+		return timeMethods
+	}
+	return nil
+}
+
+// extract returns the value of the attribute in the message as a string (used in the trace) and whether the attribute exists in the message
+func (a *Attribute) extract(message *MessageAttributes) (string, bool) {
+	switch a.Type {
+	case StringAttribute:
+		return a.String(message)
+	case IntAttribute:
+		value, exists := a.Int(message)
+		return strconv.FormatInt(value, 10), exists
+	case FloatAttribute:
+		value, exists := a.Float(message)
+		return strconv.FormatFloat(value, 'g', -1, 64), exists
+	case DurationAttribute:
+		value, exists := a.Duration(message)
+		return value.String(), exists
+	case IPAttribute:
+		value, exists := a.IP(message)
+		if value == nil {
+			return "", exists
+		}
+		return value.String(), exists
+	case TimeAttribute:
+		value, exists := a.Time(message)
+		return formatTime(value), exists
+	}
+	return "", false
+}
+
+// convertValue converts the condition's value to the attribute's type
+func (a *Attribute) convertValue(c *Condition) error {
+	if isExistenceMethod(c.Method) { // the value is not used
+		return nil
+	}
+	if isListMethod(c.Method) && a.Type != TimeAttribute {
+		return a.convertValueList(c)
+	}
+	switch a.Type {
+	case IntAttribute:
+		if _, err := strconv.ParseInt(c.Value, 10, 64); err != nil {
+			return fmt.Errorf("%w: %q is not an int", ErrInvalidValue, c.Value)
+		}
+	case FloatAttribute:
+		if _, err := strconv.ParseFloat(c.Value, 64); err != nil {
+			return fmt.Errorf("%w: %q is not a number", ErrInvalidValue, c.Value)
+		}
+	case DurationAttribute:
+		d, err := time.ParseDuration(c.Value)
+		if err != nil {
+			return fmt.Errorf("%w: %q is not a duration (example: 250ms)", ErrInvalidValue, c.Value)
+		}
+		c.ValueDuration = d
+	case IPAttribute:
+		if isCIDRMethod(c.Method) {
+			cidrs, err := parseCIDRList(c.Value)
+			if err != nil {
+				return err
+			}
+			c.ValueCIDRs = cidrs
+			return nil
+		}
+		ip := net.ParseIP(c.Value)
+		if ip == nil {
+			return fmt.Errorf("%w: %q is not an IP", ErrInvalidValue, c.Value)
+		}
+		c.ValueIP = ip
+	case TimeAttribute:
+		return convertTimeValue(c)
+	}
+	return nil
+}
+
+// convertValueList converts the list of values of the IN and NIN methods (or the range of the BETWEEN and NBETWEEN methods)
+func (a *Attribute) convertValueList(c *Condition) error {
+	values := splitValueList(c.Value)
+	if len(values) == 0 {
+		return fmt.Errorf("%w: empty list", ErrInvalidValue)
+	}
+	isRange := isMethodInList(c.Method, []string{"BETWEEN", "NBETWEEN"})
+	if isRange && len(values) != 2 {
+		return fmt.Errorf("%w: %q is not a range of two values (example: 1024;4096)", ErrInvalidValue, c.Value)
+	}
+	c.ValueIntList, c.ValueFloatList = nil, nil
+	for _, value := range values {
+		switch a.Type {
+		case StringAttribute: // the list is converted to ValueStringRegex
+			return nil
+		case IntAttribute:
+			v, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return fmt.Errorf("%w: %q is not an int", ErrInvalidValue, value)
+			}
+			c.ValueIntList = append(c.ValueIntList, v)
+		case FloatAttribute:
+			v, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fmt.Errorf("%w: %q is not a number", ErrInvalidValue, value)
+			}
+			c.ValueFloatList = append(c.ValueFloatList, v)
+		case DurationAttribute:
+			v, err := time.ParseDuration(value)
+			if err != nil {
+				return fmt.Errorf("%w: %q is not a duration (example: 250ms)", ErrInvalidValue, value)
+			}
+			c.ValueIntList = append(c.ValueIntList, int64(v))
+		default:
+			return fmt.Errorf("%w: list of values of a %v attribute", ErrInvalidValue, a.Type)
+		}
+	}
+	if isRange && ((len(c.ValueIntList) == 2 && c.ValueIntList[0] > c.ValueIntList[1]) || (len(c.ValueFloatList) == 2 && c.ValueFloatList[0] > c.ValueFloatList[1])) {
+		return fmt.Errorf("%w: %q is an empty range", ErrInvalidValue, c.Value)
+	}
+	return nil
+}
+
+// test tests the condition with the value of the attribute extracted from the message. It returns the result and the value (as a string).
+func (a *Attribute) test(c *Condition, message *MessageAttributes) (bool, string) {
+	switch a.Type {
+	case StringAttribute:
+		value, _ := a.String(message)
+		if c.Method == "RE" || c.Method == "re" || c.Method == "NRE" || c.Method == "nre" {
+			return compareRegexFunc(value, c.Method, c.ValueRegex), value
+		}
+		return compareStringWithWildcardsFunc(value, c.Method, c.ValueStringRegex), value
+	case IntAttribute:
+		value, _ := a.Int(message)
+		if isListMethod(c.Method) {
+			return compareIntListFunc(value, c.Method, c.ValueIntList), strconv.FormatInt(value, 10)
+		}
+		return compareIntFunc(value, c.Method, c.ValueInt), strconv.FormatInt(value, 10)
+	case FloatAttribute:
+		value, _ := a.Float(message)
+		if isListMethod(c.Method) {
+			return compareFloatListFunc(value, c.Method, c.ValueFloatList), strconv.FormatFloat(value, 'g', -1, 64)
+		}
+		return compareFloatFunc(value, c.Method, c.ValueFloat), strconv.FormatFloat(value, 'g', -1, 64)
+	case DurationAttribute:
+		value, _ := a.Duration(message)
+		if isListMethod(c.Method) {
+			return compareIntListFunc(int64(value), c.Method, c.ValueIntList), value.String()
+		}
+		return compareIntFunc(int64(value), c.Method, int64(c.ValueDuration)), value.String()
+	case IPAttribute:
+		value, _ := a.IP(message)
+		str := ""
+		if value != nil {
+			str = value.String()
+		}
+		if isCIDRMethod(c.Method) {
+			return compareCIDRFunc(value, c.Method, c.ValueCIDRs), str
+		}
+		return compareIPFunc(value, c.Method, c.ValueIP), str
+	case TimeAttribute:
+		value, exists := a.Time(message)
+		if !exists { // the methods are not tested with a zero time
+			return false, ""
+		}
+		return compareTimeFunc(value, c), formatTime(value)
+	}
+	return false, ""
+}
+
+// helpers for the built-in attributes. strings do not exist when empty and ints do not exist when zero.
+
+func stringField(f func(message *MessageAttributes) string) func(message *MessageAttributes) (string, bool) {
+	return func(message *MessageAttributes) (string, bool) {
+		value := f(message)
+		return value, value != ""
+	}
+}
+
+func intField(f func(message *MessageAttributes) int64) func(message *MessageAttributes) (int64, bool) {
+	return func(message *MessageAttributes) (int64, bool) {
+		value := f(message)
+		return value, value != 0
+	}
+}
+
+// timeField is used for values that are extracted from the request time
+func timeField(f func(message *MessageAttributes) float64) func(message *MessageAttributes) (float64, bool) {
+	return func(message *MessageAttributes) (float64, bool) {
+		return f(message), message.RequestTime != ""
+	}
+}
+
+// isListMethod returns true for methods with a list of values separated by ';' (IN, NIN) or a range of two values (BETWEEN, NBETWEEN)
+func isListMethod(method string) bool {
+	return isMethodInList(method, []string{"IN", "NIN", "BETWEEN", "NBETWEEN"})
+}
+
+// splitValueList splits a list of values separated by ';'. Spaces around the values and empty values are removed.
+func splitValueList(value string) []string {
+	values := []string{}
+	for _, v := range strings.Split(value, ";") {
+		v = strings.TrimSpace(v)
+		if v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+func isCIDRMethod(method string) bool {
+	return isMethodInList(method, []string{"IN_CIDR", "NOT_IN_CIDR"})
+}
+
+// parseCIDRList parses a list of CIDRs separated by ';' or ','. An IP without a prefix length is a network of one address.
+// example: "10.20.0.0/16;2001:db8::/32;192.168.1.7"
+func parseCIDRList(value string) ([]*net.IPNet, error) {
+	cidrs := []*net.IPNet{}
+	for _, str := range strings.FieldsFunc(value, func(r rune) bool { return r == ';' || r == ',' }) {
+		str = strings.TrimSpace(str)
+		if str == "" {
+			continue
+		}
+		if !strings.Contains(str, "/") {
+			ip := net.ParseIP(str)
+			if ip == nil {
+				return nil, fmt.Errorf("%w: %q is not an IP or CIDR", ErrInvalidValue, str)
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			cidrs = append(cidrs, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, cidr, err := net.ParseCIDR(str)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q is not an IP or CIDR", ErrInvalidValue, str)
+		}
+		cidrs = append(cidrs, cidr)
+	}
+	if len(cidrs) == 0 {
+		return nil, fmt.Errorf("%w: empty list of CIDRs", ErrInvalidValue)
+	}
+	return cidrs, nil
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+// the names of the gRPC status codes
+var grpcStatusCodes = map[string]int64{
+	"OK": 0, "CANCELLED": 1, "UNKNOWN": 2, "INVALID_ARGUMENT": 3, "DEADLINE_EXCEEDED": 4, "NOT_FOUND": 5, "ALREADY_EXISTS": 6,
+	"PERMISSION_DENIED": 7, "RESOURCE_EXHAUSTED": 8, "FAILED_PRECONDITION": 9, "ABORTED": 10, "OUT_OF_RANGE": 11,
+	"UNIMPLEMENTED": 12, "INTERNAL": 13, "UNAVAILABLE": 14, "DATA_LOSS": 15, "UNAUTHENTICATED": 16,
+}
+
+// grpcStatusCode converts a gRPC status given as a code or a name to the code. The status does not exist if it is empty or unknown.
+func grpcStatusCode(status string) (int64, bool) {
+	status = strings.TrimSpace(status)
+	if code, err := strconv.ParseInt(status, 10, 64); err == nil {
+		return code, true
+	}
+	code, ok := grpcStatusCodes[strings.ToUpper(status)]
+	return code, ok
+}
+
+func mustRegisterAttribute(attribute Attribute) {
+	err := RegisterAttribute(attribute)
+	if err != nil {
+		panic(err.Error())
+	}
+}
+
+// the built-in attributes: all of the MessageAttributes fields and the values extracted from them
+func init() {
+	type stringAttribute struct {
+		keyword, field, description string
+		f                           func(message *MessageAttributes) string
+	}
+	for _, a := range []stringAttribute{
+		{"sourceUid", "SourceUid", "platform-specific unique identifier for the client instance of the source service", func(m *MessageAttributes) string { return m.SourceUid }},
+		{"sourceType", "SourceType", "source workload instance type", func(m *MessageAttributes) string { return m.SourceType }},
+		{"sourceName", "SourceName", "source workload instance name", func(m *MessageAttributes) string { return m.SourceName }},
+		{"sourceNamespace", "SourceNamespace", "source workload instance namespace", func(m *MessageAttributes) string { return m.SourceNamespace }},
+		{"sourcePrincipal", "SourcePrincipal", "authority under which the source workload instance is running", func(m *MessageAttributes) string { return m.SourcePrincipal }},
+		{"sourceOwner", "SourceOwner", "reference to the workload controlling the source workload instance", func(m *MessageAttributes) string { return m.SourceOwner }},
+		{"sourceWorkloadUid", "SourceWorkloadUid", "unique identifier of the source workload", func(m *MessageAttributes) string { return m.SourceWorkloadUid }},
+		{"sourceWorkloadName", "SourceWorkloadName", "source workload name", func(m *MessageAttributes) string { return m.SourceWorkloadName }},
+		{"sourceWorkloadNamespace", "SourceWorkloadNamespace", "source workload namespace", func(m *MessageAttributes) string { return m.SourceWorkloadNamespace }},
+		{"sourceService", "SourceService", "the sender service identifier", func(m *MessageAttributes) string { return m.SourceService }},
+		{"destinationUid", "DestinationUid", "platform-specific unique identifier for the server instance of the destination service", func(m *MessageAttributes) string { return m.DestinationUid }},
+		{"destinationType", "DestinationType", "destination workload instance type", func(m *MessageAttributes) string { return m.DestinationType }},
+		{"destinationName", "DestinationName", "destination workload instance name", func(m *MessageAttributes) string { return m.DestinationName }},
+		{"destinationNamespace", "DestinationNamespace", "destination workload instance namespace", func(m *MessageAttributes) string { return m.DestinationNamespace }},
+		{"destinationPrincipal", "DestinationPrincipal", "authority under which the destination workload instance is running", func(m *MessageAttributes) string { return m.DestinationPrincipal }},
+		{"destinationOwner", "DestinationOwner", "reference to the workload controlling the destination workload instance", func(m *MessageAttributes) string { return m.DestinationOwner }},
+		{"destinationWorkloadUid", "DestinationWorkloadUid", "unique identifier of the destination workload", func(m *MessageAttributes) string { return m.DestinationWorkloadUid }},
+		{"destinationWorkloadName", "DestinationWorkloadName", "destination workload name", func(m *MessageAttributes) string { return m.DestinationWorkloadName }},
+		{"destinationWorkloadNamespace", "DestinationWorkloadNamespace", "destination workload namespace", func(m *MessageAttributes) string { return m.DestinationWorkloadNamespace }},
+		{"destinationService", "DestinationService", "the receiver service identifier", func(m *MessageAttributes) string { return m.DestinationService }},
+		{"requestPath", "RequestPath", "the HTTP URL path including query string", func(m *MessageAttributes) string { return m.RequestPath }},
+		{"requestHost", "RequestHost", "HTTP/1.x host header or HTTP/2 authority header", func(m *MessageAttributes) string { return m.RequestHost }},
+		{"requestMethod", "RequestMethod", "the HTTP method", func(m *MessageAttributes) string { return m.RequestMethod }},
+		{"requestScheme", "RequestScheme", "URI scheme of the request", func(m *MessageAttributes) string { return m.RequestScheme }},
+		{"requestTime", "RequestTime", "the timestamp when the destination receives the request", func(m *MessageAttributes) string { return m.RequestTime }},
+		{"requestUseragent", "RequestUseragent", "the HTTP User-Agent header", func(m *MessageAttributes) string { return m.RequestUseragent }},
+		{"responseTime", "ResponseTime", "the timestamp when the destination produced the response", func(m *MessageAttributes) string { return m.ResponseTime }},
+		{"responseGrpcStatus", "ResponseGrpcStatus", "the response's gRPC status", func(m *MessageAttributes) string { return m.ResponseGrpcStatus }},
+		{"responseGrpcMessage", "ResponseGrpcMessage", "the response's gRPC status message", func(m *MessageAttributes) string { return m.ResponseGrpcMessage }},
+		{"connectionMtls", "ConnectionMtls", "whether the request is received over a mutual TLS enabled downstream connection", func(m *MessageAttributes) string { return m.ConnectionMtls }},
+		{"connectionRequestedServerName", "ConnectionRequestedServerName", "the requested server name (SNI) of the connection", func(m *MessageAttributes) string { return m.ConnectionRequestedServerName }},
+		{"requestProtocol", "ContextProtocol", "protocol of the request or connection being proxied", func(m *MessageAttributes) string { return m.ContextProtocol }},
+		{"requestType", "ContextType", "type of context in relation to the protocol (the resource type)", func(m *MessageAttributes) string { return m.ContextType }},
+		{"grpcService", "RequestPath", "the gRPC service of the request path /package.Service/Method (package.Service)", func(m *MessageAttributes) string { return m.GrpcService }},
+		{"grpcMethod", "RequestPath", "the gRPC method of the request path /package.Service/Method (Method)", func(m *MessageAttributes) string { return m.GrpcMethod }},
+		{"kafkaTopic", "KafkaTopic", "the kafka topic", func(m *MessageAttributes) string { return m.KafkaTopic }},
+		{"kafkaConsumerGroup", "KafkaConsumerGroup", "the kafka consumer group", func(m *MessageAttributes) string { return m.KafkaConsumerGroup }},
+		{"messageId", "MessageID", "the message identifier", func(m *MessageAttributes) string { return m.MessageID }},
+	} {
+		mustRegisterAttribute(Attribute{Keyword: a.keyword, Type: StringAttribute, Field: a.field, Description: a.description, String: stringField(a.f)})
+	}
+
+	type intAttribute struct {
+		keyword, field, description string
+		f                           func(message *MessageAttributes) int64
+	}
+	for _, a := range []intAttribute{
+		{"payloadSize", "RequestSize", "size of the request in bytes (same as requestSize)", func(m *MessageAttributes) int64 { return m.RequestSize }},
+		{"requestSize", "RequestSize", "size of the request in bytes", func(m *MessageAttributes) int64 { return m.RequestSize }},
+		{"requestTotalSize", "RequestTotalSize", "total size of the HTTP request in bytes, including request headers, body and trailers", func(m *MessageAttributes) int64 { return m.RequestTotalSize }},
+		{"responseSize", "ResponseSize", "size of the response body in bytes", func(m *MessageAttributes) int64 { return m.ResponseSize }},
+		{"responseTotalSize", "ResponseTotalSize", "total size of the HTTP response in bytes, including response headers and body", func(m *MessageAttributes) int64 { return m.ResponseTotalSize }},
+		{"responseCode", "ResponseCode", "the response's HTTP status code", func(m *MessageAttributes) int64 { return m.ResponseCode }},
+	} {
+		mustRegisterAttribute(Attribute{Keyword: a.keyword, Type: IntAttribute, Field: a.field, Description: a.description, Int: intField(a.f)})
+	}
+
+	mustRegisterAttribute(Attribute{Keyword: "destinationPort", Type: IntAttribute, Field: "DestinationPort", Description: "the recipient port on the server IP address",
+		Int: func(m *MessageAttributes) (int64, bool) {
+			port, err := strconv.ParseInt(m.DestinationPort, 10, 64)
+			return port, err == nil
+		}})
+	mustRegisterAttribute(Attribute{Keyword: "responseGrpcStatusCode", Type: IntAttribute, Field: "ResponseGrpcStatus", Description: "the code of the response's gRPC status (the status may be given as a code or as a name, for example 14 or UNAVAILABLE)",
+		Int: func(m *MessageAttributes) (int64, bool) { return grpcStatusCode(m.ResponseGrpcStatus) }})
+	mustRegisterAttribute(Attribute{Keyword: "minuteParity", Type: IntAttribute, Field: "RequestTime", Description: "parity of the minutes of the request time (used in the istio demo)",
+		Int: func(m *MessageAttributes) (int64, bool) { return m.RequestTimeMinutesParity, m.RequestTime != "" }})
+
+	mustRegisterAttribute(Attribute{Keyword: "utcHoursFromMidnight", Type: FloatAttribute, Field: "RequestTime", Description: "hours from midnight (UTC) of the request time",
+		Float: timeField(func(m *MessageAttributes) float64 { return m.RequestTimeHoursFromMidnightUTC })})
+	mustRegisterAttribute(Attribute{Keyword: "utcMinutesFromMidnight", Type: FloatAttribute, Field: "RequestTime", Description: "minutes from midnight (UTC) of the request time",
+		Float: timeField(func(m *MessageAttributes) float64 { return m.RequestTimeMinutesFromMidnightUTC })})
+	mustRegisterAttribute(Attribute{Keyword: "utcSecondsFromMidnight", Type: FloatAttribute, Field: "RequestTime", Description: "seconds from midnight (UTC) of the request time",
+		Float: timeField(func(m *MessageAttributes) float64 { return m.RequestTimeSecondsFromMidnightUTC })})
+
+	mustRegisterAttribute(Attribute{Keyword: "requestTimestamp", Type: TimeAttribute, Field: "RequestTime", Description: "the request time (compared with timestamps, dates, recurring windows and cron expressions)",
+		Time: func(m *MessageAttributes) (time.Time, bool) {
+			return m.RequestTimeParsed, !m.RequestTimeParsed.IsZero()
+		}})
+	mustRegisterAttribute(Attribute{Keyword: "utcDayOfWeek", Type: StringAttribute, Field: "RequestTime", Description: "day of week (UTC) of the request time (Sun, Mon, Tue, Wed, Thu, Fri, Sat)",
+		String: func(m *MessageAttributes) (string, bool) {
+			if m.RequestTimeParsed.IsZero() {
+				return "", false
+			}
+			return m.RequestTimeParsed.UTC().Weekday().String()[:3], true
+		}})
+
+	mustRegisterAttribute(Attribute{Keyword: "responseDuration", Type: DurationAttribute, Field: "ResponseDuration", Description: "the amount of time the response took to generate (example value: 250ms)",
+		Duration: func(m *MessageAttributes) (time.Duration, bool) { return m.ResponseDuration, m.ResponseDuration != 0 }})
+
+	mustRegisterAttribute(Attribute{Keyword: "sourceIp", Type: IPAttribute, Field: "SourceIp", Description: "client IP address",
+		IP: func(m *MessageAttributes) (net.IP, bool) { return m.SourceNetIp, m.SourceNetIp != nil }})
+	mustRegisterAttribute(Attribute{Keyword: "destinationIp", Type: IPAttribute, Field: "DestinationIp", Description: "server IP address",
+		IP: func(m *MessageAttributes) (net.IP, bool) { return m.DestinationNetIp, m.DestinationNetIp != nil }})
+}
@@ -0,0 +1,287 @@
+package MAPL_engine
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// unregisterAttribute removes an attribute that was registered by a test (the schema and the documentation are generated from the registry)
+func unregisterAttribute(keyword string) {
+	attributeRegistry.Lock()
+	defer attributeRegistry.Unlock()
+	delete(attributeRegistry.attributes, keyword)
+}
+
+// conditionRules returns the rules of strictRules (with any path) with one condition
+func conditionRules(attribute, method, value string) string {
+	rules := strings.Replace(strictRules, `resourceName: "/books/*"`, `resourceName: "/*"`, 1)
+	return strings.Replace(rules, "    decision: allow", `    DNFconditions:
+      - ANDconditions:
+        - attribute: `+attribute+`
+          method: `+method+`
+          value: "`+value+`"
+    decision: allow`, 1)
+}
+
+func conditionMessage() *MessageAttributes {
+	return &MessageAttributes{SourceService: "A.my_namespace", DestinationService: "B.my_namespace",
+		ContextProtocol: "http", ContextType: "httpPath", RequestPath: "/books", RequestMethod: "GET"}
+}
+
+func TestRegisterAttribute(t *testing.T) {
+	tenant := Attribute{Keyword: "testTenant", Type: StringAttribute, Field: "SourceNamespace", Description: "the tenant",
+		String: func(m *MessageAttributes) (string, bool) { return m.SourceNamespace, m.SourceNamespace != "" }}
+	if err := RegisterAttribute(tenant); err != nil {
+		t.Fatal(err)
+	}
+	defer unregisterAttribute("testTenant")
+
+	if attribute, ok := LookupAttribute("testTenant"); !ok || attribute.Field != "SourceNamespace" || attribute.Type != StringAttribute {
+		t.Errorf("unexpected attribute %+v", attribute)
+	}
+	found := false
+	for _, attribute := range Attributes() {
+		found = found || attribute.Keyword == "testTenant"
+	}
+	if !found {
+		t.Errorf("the attribute is not in the list of attributes")
+	}
+	if _, ok := LookupAttribute("testUnknown"); ok {
+		t.Errorf("expected an unknown attribute")
+	}
+
+	intExtractor := func(m *MessageAttributes) (int64, bool) { return 0, true }
+	tests := []struct {
+		attribute Attribute
+		err       error
+	}{
+		{tenant, ErrAttributeRegistered},
+		{Attribute{Keyword: "payloadSize", Type: IntAttribute, Int: intExtractor}, ErrAttributeRegistered}, // a built-in attribute
+		{Attribute{Keyword: "senderLabel", Type: IntAttribute, Int: intExtractor}, ErrAttributeRegistered}, // a reserved keyword
+		{Attribute{Keyword: "", Type: IntAttribute, Int: intExtractor}, ErrInvalidAttribute},
+		{Attribute{Keyword: "test:size", Type: IntAttribute, Int: intExtractor}, ErrInvalidAttribute},
+		{Attribute{Keyword: "test size", Type: IntAttribute, Int: intExtractor}, ErrInvalidAttribute},
+		{Attribute{Keyword: "testSize[0]", Type: IntAttribute, Int: intExtractor}, ErrInvalidAttribute},
+		{Attribute{Keyword: "testSize", Type: IntAttribute}, ErrInvalidAttribute},                       // no extractor
+		{Attribute{Keyword: "testSize", Type: StringAttribute, Int: intExtractor}, ErrInvalidAttribute}, // an extractor of another type
+		{Attribute{Keyword: "testSize", Type: IntAttribute, Int: intExtractor, String: tenant.String}, ErrInvalidAttribute},
+	}
+	for _, test := range tests {
+		if err := RegisterAttribute(test.attribute); !errors.Is(err, test.err) {
+			t.Errorf("%q: expected %v, got %v", test.attribute.Keyword, test.err, err)
+		}
+	}
+	if _, ok := LookupAttribute("testSize"); ok {
+		t.Errorf("an invalid attribute was registered")
+	}
+}
+
+// TestAttributeExtractors tests conditions of registered attributes of each type
+func TestAttributeExtractors(t *testing.T) {
+	attributes := []Attribute{
+		{Keyword: "testString", Type: StringAttribute, String: func(m *MessageAttributes) (string, bool) { return m.SourceNamespace, m.SourceNamespace != "" }},
+		{Keyword: "testInt", Type: IntAttribute, Int: func(m *MessageAttributes) (int64, bool) { return m.RequestSize, true }}, // a zero size exists
+		{Keyword: "testFloat", Type: FloatAttribute, Float: func(m *MessageAttributes) (float64, bool) { return float64(m.RequestSize) / 1024, m.RequestSize != 0 }},
+		{Keyword: "testDuration", Type: DurationAttribute, Duration: func(m *MessageAttributes) (time.Duration, bool) { return m.ResponseDuration, m.ResponseDuration != 0 }},
+		{Keyword: "testIP", Type: IPAttribute, IP: func(m *MessageAttributes) (net.IP, bool) { return m.SourceNetIp, m.SourceNetIp != nil }},
+		{Keyword: "testTime", Type: TimeAttribute, Time: func(m *MessageAttributes) (time.Time, bool) {
+			return m.RequestTimeParsed, !m.RequestTimeParsed.IsZero()
+		}},
+	}
+	for _, attribute := range attributes {
+		if err := RegisterAttribute(attribute); err != nil {
+			t.Fatal(err)
+		}
+		defer unregisterAttribute(attribute.Keyword)
+	}
+
+	message := conditionMessage()
+	message.SourceNamespace = "tenant-a"
+	message.RequestSize = 2048
+	message.ResponseDuration = 300 * time.Millisecond
+	message.SourceNetIp = net.ParseIP("10.20.1.7")
+	message.RequestTimeParsed = time.Date(2026, 11, 2, 10, 0, 0, 0, time.UTC)
+	empty := conditionMessage()
+
+	tests := []struct {
+		attribute, method, value string
+		message                  *MessageAttributes
+		decision                 int
+	}{
+		{"testString", "EQ", "tenant-*", message, ALLOW},
+		{"testString", "RE", "^tenant-[b-z]$", message, DEFAULT},
+		{"testString", "IN", "tenant-b;tenant-a", message, ALLOW},
+		{"testInt", "GT", "1024", message, ALLOW},
+		{"testInt", "BETWEEN", "0;1024", message, DEFAULT},
+		{"testFloat", "EQ", "2", message, ALLOW},
+		{"testFloat", "LT", "1.5", message, DEFAULT},
+		{"testDuration", "GE", "250ms", message, ALLOW},
+		{"testDuration", "LT", "0.25s", message, DEFAULT},
+		{"testIP", "IN_CIDR", "10.20.0.0/16", message, ALLOW},
+		{"testIP", "EQ", "10.20.1.8", message, DEFAULT},
+		{"testTime", "AFTER", "2026-11-01T00:00:00Z", message, ALLOW},
+		{"testTime", "BEFORE", "2026-11-01T00:00:00Z", message, DEFAULT},
+		{"testTime", "AFTER", "2026-11-01T00:00:00Z", empty, DEFAULT}, // the time methods are not tested with a zero time
+
+		// the existence is returned by the extractor
+		{"testString", "EX", "", message, ALLOW},
+		{"testString", "EX", "", empty, DEFAULT},
+		{"testInt", "EX", "", empty, ALLOW},
+		{"testFloat", "NEX", "", empty, ALLOW},
+		{"testDuration", "NEX", "", message, DEFAULT},
+		{"testIP", "NEX", "", empty, ALLOW},
+		{"testTime", "EX", "", message, ALLOW},
+	}
+	for _, test := range tests {
+		rules, err := ParseRules([]byte(conditionRules(test.attribute, test.method, test.value)))
+		if err != nil {
+			t.Fatalf("%v %v %v: %v", test.attribute, test.method, test.value, err)
+		}
+		if decision, _, _, _, _ := Check(test.message, rules); decision != test.decision {
+			t.Errorf("%v %v %v: expected decision %v, got %v", test.attribute, test.method, test.value, test.decision, decision)
+		}
+	}
+}
+
+// TestAttributeExistence tests the EX and NEX methods of the built-in attributes with empty strings, zero ints and a zero time
+func TestAttributeExistence(t *testing.T) {
+	message := conditionMessage()
+	message.SourceNamespace = "my_namespace"
+	message.RequestSize = 10
+	message.RequestTime = "2026-11-02T10:00:00Z"
+	message.RequestTimeParsed = time.Date(2026, 11, 2, 10, 0, 0, 0, time.UTC)
+	empty := conditionMessage() // an empty namespace, a zero size and no request time
+
+	tests := []struct {
+		attribute string
+		message   *MessageAttributes
+		exists    bool
+	}{
+		{"sourceNamespace", message, true},
+		{"sourceNamespace", empty, false},
+		{"requestSize", message, true},
+		{"requestSize", empty, false},
+		{"requestTimestamp", message, true},
+		{"requestTimestamp", empty, false},
+		{"utcHoursFromMidnight", message, true},
+		{"utcHoursFromMidnight", empty, false},
+	}
+	for _, test := range tests {
+		for _, method := range []string{"EX", "NEX"} {
+			rules, err := ParseRules([]byte(conditionRules(test.attribute, method, "")))
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected := DEFAULT
+			if test.exists == (method == "EX") {
+				expected = ALLOW
+			}
+			if decision, _, _, _, _ := Check(test.message, rules); decision != expected {
+				t.Errorf("%v %v (exists: %v): expected decision %v, got %v", test.attribute, method, test.exists, expected, decision)
+			}
+		}
+	}
+}
+
+func TestParseCIDRList(t *testing.T) {
+	tests := []struct {
+		value string
+		cidrs []string
+	}{
+		{"10.20.0.0/16", []string{"10.20.0.0/16"}},
+		{"10.20.1.7/16", []string{"10.20.0.0/16"}},
+		{"fd00:20::/64", []string{"fd00:20::/64"}},
+		{"192.168.1.7", []string{"192.168.1.7/32"}}, // an ip without a prefix length is a network of one address
+		{"2001:db8::1", []string{"2001:db8::1/128"}},
+		{"10.20.0.0/16, 192.168.1.7;fd00:20::/64;", []string{"10.20.0.0/16", "192.168.1.7/32", "fd00:20::/64"}},
+	}
+	for _, test := range tests {
+		cidrs, err := parseCIDRList(test.value)
+		if err != nil {
+			t.Errorf("%q: %v", test.value, err)
+			continue
+		}
+		strs := []string{}
+		for _, cidr := range cidrs {
+			strs = append(strs, cidr.String())
+		}
+		if strings.Join(strs, " ") != strings.Join(test.cidrs, " ") {
+			t.Errorf("%q: unexpected networks %v", test.value, strs)
+		}
+	}
+
+	for _, value := range []string{"", " ; ", "10.0.0.0/33", "10.0.0.300", "fd00::/129", "10.0.0.0/8;nope", "10.0.0.0/8/8"} {
+		if _, err := parseCIDRList(value); !errors.Is(err, ErrInvalidValue) {
+			t.Errorf("%q: expected an invalid value error, got %v", value, err)
+		}
+		_, err := ParseRules([]byte(conditionRules("sourceIp", "IN_CIDR", value)))
+		ruleErr, ok := err.(*RuleError)
+		if !ok || !errors.Is(err, ErrInvalidValue) || ruleErr.Path != "rules[0].DNFconditions[0].ANDconditions[0].value" {
+			t.Errorf("%q: expected an invalid value error when the rules are read, got %v", value, err)
+		}
+	}
+}
+
+// TestCIDRConditions tests the IN_CIDR and NOT_IN_CIDR methods of the source and destination ips
+func TestCIDRConditions(t *testing.T) {
+	tests := []struct {
+		attribute, method, value string
+		ip                       string // the ip of the attribute in the message ("" for a message without it)
+		decision                 int
+	}{
+		{"sourceIp", "IN_CIDR", "10.20.0.0/16", "10.20.3.4", ALLOW},
+		{"sourceIp", "IN_CIDR", "10.20.0.0/16", "10.21.0.1", DEFAULT},
+		{"sourceIp", "NOT_IN_CIDR", "10.20.0.0/16", "10.21.0.1", ALLOW},
+		{"sourceIp", "not_in_cidr", "10.20.0.0/16", "10.20.3.4", DEFAULT},
+
+		// IPv6
+		{"sourceIp", "IN_CIDR", "fd00:20::/64", "fd00:20::5", ALLOW},
+		{"sourceIp", "IN_CIDR", "fd00:20::/64", "fd00:21::5", DEFAULT},
+		{"destinationIp", "NOT_IN_CIDR", "fd00:20::/64", "fd00:21::5", ALLOW},
+
+		// IPv4 ips are not in IPv6 networks and IPv6 ips are not in IPv4 networks (IPv4-mapped IPv6 ips are IPv4 ips)
+		{"sourceIp", "IN_CIDR", "::/0", "10.20.3.4", DEFAULT},
+		{"sourceIp", "NOT_IN_CIDR", "::/0", "10.20.3.4", ALLOW},
+		{"sourceIp", "IN_CIDR", "0.0.0.0/0", "fd00:20::5", DEFAULT},
+		{"sourceIp", "NOT_IN_CIDR", "0.0.0.0/0", "fd00:20::5", ALLOW},
+		{"sourceIp", "IN_CIDR", "10.20.0.0/16", "::ffff:10.20.3.4", ALLOW},
+
+		// ips without a prefix length
+		{"destinationIp", "IN_CIDR", "192.168.1.7", "192.168.1.7", ALLOW},
+		{"destinationIp", "IN_CIDR", "192.168.1.7", "192.168.1.8", DEFAULT},
+		{"destinationIp", "IN_CIDR", "2001:db8::1", "2001:db8::1", ALLOW},
+
+		// lists separated by ';' and ','
+		{"destinationIp", "IN_CIDR", "10.20.0.0/16, 192.168.1.7;fd00:20::/64", "192.168.1.7", ALLOW},
+		{"destinationIp", "IN_CIDR", "10.20.0.0/16, 192.168.1.7;fd00:20::/64", "fd00:20::5", ALLOW},
+		{"destinationIp", "IN_CIDR", "10.20.0.0/16, 192.168.1.7;fd00:20::/64", "10.20.3.4", ALLOW},
+		{"destinationIp", "NOT_IN_CIDR", "10.20.0.0/16, 192.168.1.7;fd00:20::/64", "10.21.0.1", ALLOW},
+		{"destinationIp", "NOT_IN_CIDR", "10.20.0.0/16, 192.168.1.7;fd00:20::/64", "fd00:20::5", DEFAULT},
+
+		// both methods are false for a message without the ip
+		{"sourceIp", "IN_CIDR", "10.20.0.0/16", "", DEFAULT},
+		{"sourceIp", "NOT_IN_CIDR", "10.20.0.0/16", "", DEFAULT},
+		{"sourceIp", "NEX", "", "", ALLOW},
+		{"destinationIp", "IN_CIDR", "0.0.0.0/0;::/0", "", DEFAULT},
+		{"destinationIp", "NOT_IN_CIDR", "10.20.0.0/16", "", DEFAULT},
+		{"destinationIp", "NEX", "", "", ALLOW},
+	}
+	for _, test := range tests {
+		rules, err := ParseRules([]byte(conditionRules(test.attribute, test.method, test.value)))
+		if err != nil {
+			t.Fatalf("%v %v %q: %v", test.attribute, test.method, test.value, err)
+		}
+		message := conditionMessage()
+		if test.attribute == "sourceIp" {
+			message.SourceIp, message.SourceNetIp = test.ip, net.ParseIP(test.ip)
+		} else {
+			message.DestinationIp, message.DestinationNetIp = test.ip, net.ParseIP(test.ip)
+		}
+		if decision, _, _, _, _ := Check(message, rules); decision != test.decision {
+			t.Errorf("%v %v %q with ip %q: expected decision %v, got %v", test.attribute, test.method, test.value, test.ip, test.decision, decision)
+		}
+	}
+}
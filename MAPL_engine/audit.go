@@ -0,0 +1,91 @@
+package MAPL_engine
+
+import (
+	"fmt"
+	"strings"
+)
+
+// rule modes
+const (
+	RuleModeEnforce = "enforce"
+	RuleModeAudit   = "audit"
+)
+
+// a rule in audit mode (mode: audit) is tested with the messages as any other rule but its decision does not change the decision of the message.
+// It is used to observe the effect of a new rule before it is enforced. example:
+//
+//	  - rule_id: block-external-payments
+//	    mode: audit
+//	    sender:
+//	      ...
+//	    decision: block
+//
+// Check, CheckWithTrace and Policy.Check return the results of the audit rules (in the results list) but do not use them in the decision
+// (and in the applied rules). CheckWithAudit and Policy.CheckWithAudit also return the audit rules that apply to the message.
+
+// AuditResult is the result of an audit rule that applies to a message
+type AuditResult struct {
+	RuleIndex        int    `json:"RuleIndex"`
+	RuleID           string `json:"RuleID,omitempty"`
+	Result           int    `json:"Result"` // the decision of the rule
+	ResultString     string `json:"ResultString"`
+	WouldHaveDecided int    `json:"WouldHaveDecided"` // the decision of the message if the rule was enforced
+	Changed          bool   `json:"Changed"`          // the rule would have changed the decision of the message
+}
+
+// convertMode tests the mode of the rule
+func convertMode(rule *Rule) error {
+	if rule.Mode == "" || strings.EqualFold(rule.Mode, RuleModeEnforce) || strings.EqualFold(rule.Mode, RuleModeAudit) {
+		return nil
+	}
+	return &fieldError{"mode", fmt.Errorf("%w: %q (%v or %v)", ErrInvalidMode, rule.Mode, RuleModeEnforce, RuleModeAudit)}
+}
+
+// IsAuditRule returns true if the rule is in audit mode
+func IsAuditRule(rule *Rule) bool {
+	return strings.EqualFold(rule.Mode, RuleModeAudit)
+}
+
+// CheckWithAudit is the same as the Check function (with the same outputs) and in addition returns the results of the audit rules that apply to the message
+func CheckWithAudit(message *MessageAttributes, rules *Rules) (decision int, descisionString string, relevantRuleIndex int, results []int, appliedRulesIndices []int, auditResults []AuditResult) {
+	decision, descisionString, relevantRuleIndex, results, appliedRulesIndices = Check(message, rules)
+	return decision, descisionString, relevantRuleIndex, results, appliedRulesIndices, newAuditResults(rules, results, decision)
+}
+
+// CheckWithAudit is the same as the CheckWithAudit function but evaluates only the candidate rules of the message (as Policy.Check)
+func (p *Policy) CheckWithAudit(message *MessageAttributes) (decision int, descisionString string, relevantRuleIndex int, results []int, appliedRulesIndices []int, auditResults []AuditResult) {
+	decision, descisionString, relevantRuleIndex, results, appliedRulesIndices = p.Check(message)
+	return decision, descisionString, relevantRuleIndex, results, appliedRulesIndices, newAuditResults(p.Rules, results, decision)
+}
+
+// newAuditResults returns the results of the audit rules that apply to the message (in the order of the rules)
+func newAuditResults(rules *Rules, results []int, decision int) []AuditResult {
+	auditResults := []AuditResult{}
+	for i, result := range results {
+		if result == DEFAULT || !IsAuditRule(&rules.Rules[i]) {
+			continue
+		}
+		wouldHaveDecided := decision
+		if result > decision {
+			wouldHaveDecided = result
+		}
+		auditResults = append(auditResults, AuditResult{
+			RuleIndex:        i,
+			RuleID:           rules.Rules[i].RuleID,
+			Result:           result,
+			ResultString:     ActionTypeNames[result],
+			WouldHaveDecided: wouldHaveDecided,
+			Changed:          wouldHaveDecided != decision,
+		})
+	}
+	return auditResults
+}
+
+// String returns the audit result as text. example: "rule block-external-payments (#2): block (would have decided block)"
+func (r AuditResult) String() string {
+	str := fmt.Sprintf("rule %v (#%v): %v", r.RuleID, r.RuleIndex, r.ResultString)
+	if r.Changed {
+		str += fmt.Sprintf(" (would have decided %v)", ActionTypeNames[r.WouldHaveDecided])
+	}
+	return str
+}
@@ -0,0 +1,101 @@
+package MAPL_engine
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestCheckWithAudit checks the messages of examples/messages_audit.yaml and compares the audit results with Policy.CheckWithAudit and the trace
+func TestCheckWithAudit(t *testing.T) {
+	rules, err := ParseRulesFromFile("../examples/rules_audit.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages, err := ParseMessagesFromFile("../examples/messages_audit.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := NewPolicy(rules)
+
+	expected := [][]AuditResult{
+		{},
+		{{RuleIndex: 2, RuleID: "block-legacy-payments", Result: BLOCK, ResultString: "block", WouldHaveDecided: BLOCK, Changed: true}},
+		{{RuleIndex: 3, RuleID: "alert-on-delete", Result: ALERT, ResultString: "alert", WouldHaveDecided: ALERT, Changed: true}},
+		{{RuleIndex: 2, RuleID: "block-legacy-payments", Result: BLOCK, ResultString: "block", WouldHaveDecided: BLOCK, Changed: true}},
+	}
+	for i := range messages.Messages {
+		message := &messages.Messages[i]
+		decision, _, _, results, appliedRulesIndices, auditResults := CheckWithAudit(message, rules)
+		if !reflect.DeepEqual(auditResults, expected[i]) {
+			t.Errorf("message #%v: unexpected audit results %+v", i, auditResults)
+		}
+		for _, r := range auditResults {
+			if containsInt(appliedRulesIndices, r.RuleIndex) || results[r.RuleIndex] != r.Result {
+				t.Errorf("message #%v: unexpected results %v and applied rules %v of the audit rule %v", i, results, appliedRulesIndices, r.RuleIndex)
+			}
+		}
+
+		policyDecision, _, _, _, _, policyAuditResults := policy.CheckWithAudit(message)
+		if policyDecision != decision || !reflect.DeepEqual(policyAuditResults, auditResults) {
+			t.Errorf("message #%v: Policy.CheckWithAudit differs: %v %+v", i, policyDecision, policyAuditResults)
+		}
+
+		_, _, _, _, _, trace := CheckWithTrace(message, rules)
+		if !reflect.DeepEqual(trace.AuditResults, auditResults) || !trace.Rules[2].Audit || trace.Rules[0].Audit {
+			t.Errorf("message #%v: unexpected trace %+v", i, trace)
+		}
+	}
+
+	// an audit rule that does not change the decision
+	rules.Rules[2].Decision = "allow"
+	_, _, _, _, _, auditResults := CheckWithAudit(&messages.Messages[1], rules)
+	if len(auditResults) != 1 || auditResults[0].Changed || auditResults[0].String() != "rule block-legacy-payments (#2): allow" {
+		t.Errorf("unexpected audit results %v", auditResults)
+	}
+	if auditResults := newAuditResults(rules, []int{ALLOW, DEFAULT, BLOCK, DEFAULT}, ALLOW); auditResults[0].String() != "rule block-legacy-payments (#2): block (would have decided block)" {
+		t.Errorf("unexpected audit results %v", auditResults)
+	}
+}
+
+func TestRuleMode(t *testing.T) {
+	audit := strings.Replace(strictRules, "    decision: allow", "    decision: allow\n    mode: Audit", 1)
+	rules, err := ParseRules([]byte(audit))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsAuditRule(&rules.Rules[0]) {
+		t.Errorf("expected an audit rule")
+	}
+
+	// the mode is kept by the writers and changes the hash (enforce is the default)
+	data, err := RulesToJson(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromJson, err := ParseRulesJSON(data)
+	if err != nil || fromJson.Rules[0].Mode != "Audit" {
+		t.Errorf("the mode was not kept in json: %v", err)
+	}
+	enforce, _ := ParseRules([]byte(strings.Replace(strictRules, "    decision: allow", "    decision: allow\n    mode: enforce", 1)))
+	plain, _ := ParseRules([]byte(strictRules))
+	if RuleMD5Hash(enforce.Rules[0]) != RuleMD5Hash(plain.Rules[0]) || RuleMD5Hash(rules.Rules[0]) == RuleMD5Hash(plain.Rules[0]) {
+		t.Errorf("unexpected hashes of the rules with modes")
+	}
+
+	_, err = ParseRules([]byte(strings.Replace(strictRules, "    decision: allow", "    decision: allow\n    mode: dry-run", 1)))
+	ruleErr, ok := err.(*RuleError)
+	if !ok || !errors.Is(err, ErrInvalidMode) || ruleErr.Path != "rules[0].mode" || ruleErr.Line != 15 || ruleErr.Column != 11 {
+		t.Errorf("expected an invalid mode error, got %v", err)
+	}
+}
+
+func containsInt(list []int, value int) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,266 @@
+package MAPL_engine
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// a policy bundle is a set of rules files that are read as one list of rules. LoadBundle reads:
+//   - a directory: all of the .yaml, .yml and .json files in the directory and its sub-directories (in lexical order)
+//   - a manifest: a yaml file with a list of globs of rules files (relative to the manifest). example:
+//
+//	include:
+//	  - "teams/*.yaml"
+//	  - "default.yaml"
+//
+//   - a rules file
+//
+// Directories that are matched by the globs of a manifest are read as directories, and manifests that are included by a manifest
+// (or that are in a directory of the bundle) are read as manifests. Each file is read once.
+// yaml files may contain several documents (separated by ---), each with its rules list.
+
+// RuleSource is the place of a rule in the files of a policy bundle
+type RuleSource struct {
+	File string // the rules file (as given to LoadBundle or joined with the directory of the manifest)
+	Line int    // the line of the rule in the file
+}
+
+func (s RuleSource) String() string {
+	return fmt.Sprintf("%v:%v", s.File, s.Line)
+}
+
+// Bundle contains the rules of the files of a policy bundle
+type Bundle struct {
+	Rules   Rules
+	Sources []RuleSource // the source of each rule (by the index of the rule in Rules.Rules)
+	Files   []string     // the rules files of the bundle in the order they were read (without the manifests)
+}
+
+// BundleError describes a problem in one of the files of a policy bundle. Err is a *RuleError for errors in the rules.
+type BundleError struct {
+	File string
+	Err  error
+}
+
+func (e *BundleError) Error() string {
+	return e.File + ": " + e.Err.Error()
+}
+
+func (e *BundleError) Unwrap() error {
+	return e.Err
+}
+
+type bundleManifest struct {
+	Include []string `yaml:"include"`
+}
+
+// bundleLoader keeps the state of LoadBundle: the bundle and the files that were read
+type bundleLoader struct {
+	bundle  Bundle
+	read    map[string]bool // by the absolute path of the file
+	ruleIDs map[string]RuleSource
+}
+
+// LoadBundle reads the rules of a policy bundle from a directory, a manifest or a rules file (see Bundle).
+// The rule_ids of the rules must be unique in the bundle (rules without rule_ids are not checked).
+// The returned error is a *BundleError with the file of the error.
+func LoadBundle(path string) (*Bundle, error) {
+	loader := bundleLoader{read: map[string]bool{}, ruleIDs: map[string]RuleSource{}}
+	loader.bundle.Sources = []RuleSource{}
+	loader.bundle.Files = []string{}
+	err := loader.load(path)
+	if err != nil {
+		return nil, err
+	}
+	return &loader.bundle, nil
+}
+
+// IsBundleManifest returns true if the yaml data is a manifest of a policy bundle (a yaml mapping with the include key)
+func IsBundleManifest(data []byte) bool {
+	var root yamlv3.Node
+	if yamlv3.Unmarshal(data, &root) != nil {
+		return false
+	}
+	return yamlMappingValue(yamlTopNode(&root), "include") != nil
+}
+
+// load reads a directory, a manifest or a rules file
+func (l *bundleLoader) load(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return &BundleError{File: path, Err: err}
+	}
+	if info.IsDir() {
+		return l.loadDirectory(path)
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return &BundleError{File: path, Err: err}
+	}
+	if l.read[absPath] {
+		return nil
+	}
+	l.read[absPath] = true
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return &BundleError{File: path, Err: err}
+	}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return l.loadJSON(path, data)
+	}
+	if IsBundleManifest(data) {
+		return l.loadManifest(path, data)
+	}
+	return l.loadYaml(path, data)
+}
+
+// loadDirectory reads the rules files in the directory and its sub-directories
+func (l *bundleLoader) loadDirectory(dir string) error {
+	files := []string{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != dir && strings.HasPrefix(info.Name(), ".") { // hidden directories (for example .git)
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml", ".json":
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return &BundleError{File: dir, Err: err}
+	}
+	if len(files) == 0 {
+		return &BundleError{File: dir, Err: ErrNoBundleFiles}
+	}
+	for _, file := range files { // filepath.Walk walks in lexical order
+		err = l.load(file)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadManifest reads the files of the globs of the manifest
+func (l *bundleLoader) loadManifest(path string, data []byte) error {
+	var root yamlv3.Node
+	var manifest bundleManifest
+	err := yamlv3.Unmarshal(data, &root)
+	if err == nil {
+		err = root.Decode(&manifest)
+	}
+	if err != nil {
+		line := yamlErrorLine(yamlDecodeErrors(err)[0])
+		return &BundleError{File: path, Err: &RuleError{RuleIndex: -1, Line: line, Err: fmt.Errorf("%w: %v", ErrInvalidYaml, err)}}
+	}
+	if fieldPath, line, column, err := yamlUnknownField(&root, &manifest); err != nil {
+		return &BundleError{File: path, Err: &RuleError{RuleIndex: -1, Path: fieldPath, Line: line, Column: column, Err: err}}
+	}
+
+	dir := filepath.Dir(path)
+	for i, pattern := range manifest.Include {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(dir, pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err == nil && len(matches) == 0 {
+			err = fmt.Errorf("%w: %q", ErrNoBundleFiles, manifest.Include[i])
+		}
+		if err != nil {
+			includePath := fmt.Sprintf("include[%v]", i)
+			line, column := yamlPosition(&root, includePath)
+			return &BundleError{File: path, Err: &RuleError{RuleIndex: -1, Path: includePath, Line: line, Column: column, Err: err}}
+		}
+		sort.Strings(matches)
+		for _, match := range matches {
+			err = l.load(match)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// loadYaml reads the rules of the yaml documents of a rules file
+func (l *bundleLoader) loadYaml(path string, data []byte) error {
+	documents, err := yamlDocuments(data)
+	if err != nil {
+		return &BundleError{File: path, Err: &RuleError{RuleIndex: -1, Line: yamlErrorLine(err.Error()), Err: fmt.Errorf("%w: %v", ErrInvalidYaml, err)}}
+	}
+	for _, root := range documents {
+		rules, err := parseRulesNode(root)
+		if err != nil {
+			return &BundleError{File: path, Err: err}
+		}
+		items := yamlMappingValue(yamlTopNode(root), "rules")
+		lines := make([]int, len(rules.Rules))
+		for i := range lines {
+			lines[i] = items.Content[i].Line
+		}
+		err = l.add(path, rules, lines, func(i int) (string, int, int) {
+			ruleIDPath := fmt.Sprintf("rules[%v].rule_id", i)
+			line, column := yamlPosition(root, ruleIDPath)
+			return ruleIDPath, line, column
+		})
+		if err != nil {
+			return err
+		}
+	}
+	l.bundle.Files = append(l.bundle.Files, path)
+	return nil
+}
+
+// loadJSON reads the rules of a json rules file
+func (l *bundleLoader) loadJSON(path string, data []byte) error {
+	rules, offsets, err := parseRulesJSON(data)
+	if err != nil {
+		return &BundleError{File: path, Err: err}
+	}
+	lines := make([]int, len(rules.Rules))
+	for i, offset := range offsets {
+		lines[i], _ = jsonPosition(data, offset)
+	}
+	err = l.add(path, rules, lines, func(i int) (string, int, int) {
+		line, column := jsonPosition(data, offsets[i]) // the position of the rule
+		return fmt.Sprintf("Rules[%v].RuleID", i), line, column
+	})
+	if err != nil {
+		return err
+	}
+	l.bundle.Files = append(l.bundle.Files, path)
+	return nil
+}
+
+// add adds the rules of a file (or of a yaml document) to the bundle. ruleIDPosition returns the path, line and column of the rule_id of a rule for errors.
+func (l *bundleLoader) add(path string, rules *Rules, lines []int, ruleIDPosition func(i int) (string, int, int)) error {
+	for i, rule := range rules.Rules {
+		source := RuleSource{File: path, Line: lines[i]}
+		if rule.RuleID != "" {
+			if first, exists := l.ruleIDs[rule.RuleID]; exists {
+				ruleIDPath, line, column := ruleIDPosition(i)
+				err := fmt.Errorf("%w: %q (the rule at %v)", ErrDuplicateRuleID, rule.RuleID, first)
+				return &BundleError{File: path, Err: &RuleError{RuleIndex: i, RuleID: rule.RuleID, Path: ruleIDPath, Line: line, Column: column, Err: err}}
+			}
+			l.ruleIDs[rule.RuleID] = source
+		}
+		l.bundle.Rules.Rules = append(l.bundle.Rules.Rules, rule)
+		l.bundle.Sources = append(l.bundle.Sources, source)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package MAPL_engine
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestLoadBundle reads the bundle of the examples by its manifest and by its directory
+func TestLoadBundle(t *testing.T) {
+	messages, err := ParseMessagesFromFile("../examples/messages_bundle.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"../examples/bundle/bundle.yaml", "../examples/bundle"} {
+		bundle, err := LoadBundle(path)
+		if err != nil {
+			t.Fatalf("%v: %v", path, err)
+		}
+		if report := CheckExpectations(messages, &bundle.Rules); report.Failed > 0 {
+			t.Errorf("%v: %v", path, report.String())
+		}
+
+		sources := map[string]string{}
+		for i, rule := range bundle.Rules.Rules {
+			sources[rule.RuleID] = bundle.Sources[i].String()
+		}
+		expected := map[string]string{
+			"books-read":              "../examples/bundle/teams/books.yaml:4",
+			"books-write":             "../examples/bundle/teams/books.yaml:20",
+			"payments-block-external": "../examples/bundle/teams/payments.yaml:3",
+			"alert-on-delete":         "../examples/bundle/default.yaml:3",
+		}
+		if !reflect.DeepEqual(sources, expected) {
+			t.Errorf("%v: unexpected sources %v", path, sources)
+		}
+	}
+
+	bundle, _ := LoadBundle("../examples/bundle/bundle.yaml")
+	expectedFiles := []string{"../examples/bundle/teams/books.yaml", "../examples/bundle/teams/payments.yaml", "../examples/bundle/default.yaml"}
+	if !reflect.DeepEqual(bundle.Files, expectedFiles) {
+		t.Errorf("unexpected files %v", bundle.Files)
+	}
+}
+
+func TestLoadBundleErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	write := func(name, data string) {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a.yaml", strictRules)
+	write("b.yaml", "---\n"+strictRules+"---\n"+strictRules)
+	write("bundle.yaml", "include:\n  - a.yaml\n  - c*.yaml\n")
+	write("manifest.yaml", "include:\n  - a.yaml\n  - b.yaml\nrules: []\n")
+
+	tests := []struct {
+		path   string
+		err    error
+		file   string
+		line   int
+		column int
+	}{
+		{dir, ErrDuplicateRuleID, "b.yaml", 3, 14},
+		{filepath.Join(dir, "bundle.yaml"), ErrNoBundleFiles, "bundle.yaml", 3, 5},
+		{filepath.Join(dir, "manifest.yaml"), ErrUnknownField, "manifest.yaml", 4, 1},
+	}
+	for _, test := range tests {
+		_, err := LoadBundle(test.path)
+		bundleErr, ok := err.(*BundleError)
+		if !ok {
+			t.Fatalf("%v: expected a *BundleError, got %v", test.path, err)
+		}
+		var ruleErr *RuleError
+		if !errors.Is(err, test.err) || filepath.Base(bundleErr.File) != test.file || !errors.As(err, &ruleErr) || ruleErr.Line != test.line || ruleErr.Column != test.column {
+			t.Errorf("%v: unexpected error: %v", test.path, err)
+		}
+	}
+
+	// the rule_ids of the documents of one file
+	write("b.yaml", "---\n"+strictRules+"---\n"+strictRules+"---\n")
+	_, err = LoadBundle(filepath.Join(dir, "b.yaml"))
+	var ruleErr *RuleError
+	if !errors.Is(err, ErrDuplicateRuleID) || !errors.As(err, &ruleErr) || ruleErr.Line != 18 {
+		t.Errorf("expected a duplicate rule_id error, got %v", err)
+	}
+}
+
+// TestMultipleDocuments reads a rules file with two documents: LoadBundle reads both of them and ParseRules returns an error
+func TestMultipleDocuments(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	second := strings.Replace(strings.Replace(strictRules, "rule_id: 0", "rule_id: 1", 1), "decision: allow", "decision: block", 1)
+	path := filepath.Join(dir, "rules.yaml")
+	if err := ioutil.WriteFile(path, []byte(strictRules+"---\n"+second), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	bundle, err := LoadBundle(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := MessageAttributes{SourceService: "A.my_namespace", DestinationService: "B.my_namespace", ContextProtocol: "http", ContextType: "httpPath",
+		RequestPath: "/books/1", RequestMethod: "GET"}
+	decision, _, ruleIndex, _, _ := Check(&message, &bundle.Rules)
+	if len(bundle.Rules.Rules) != 2 || decision != BLOCK || ruleIndex != 1 || bundle.Sources[1].Line != 17 {
+		t.Errorf("unexpected decision %v by rule %v of the rules %+v", decision, ruleIndex, bundle.Sources)
+	}
+
+	_, err = ParseRulesFromFile(path)
+	ruleErr, ok := err.(*RuleError)
+	if !ok || !errors.Is(err, ErrMultipleDocuments) || ruleErr.Line != 16 || ruleErr.Column != 1 {
+		t.Errorf("expected a multiple documents error, got %v", err)
+	}
+	// empty documents are not counted
+	if _, err := ParseRules([]byte("---\n" + strictRules + "---\n")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -4,7 +4,8 @@ package MAPL_engine
 import (
 	"strings"
 	"regexp"
-	"fmt"
+	"net"
+	"time"
 )
 
 // general action codes
@@ -23,7 +24,8 @@ var ActionTypeNames = [...]string{
 }
 // Check is the main function to test if any of the rules is applicable for the message and decide according
 // to those rules' decisions.
-
+// The rules are tested one after the other. For a large number of rules, compile them once with NewPolicy and use Policy.Check
+// which tests only the candidate rules of the message.
 func Check(message *MessageAttributes, rules *Rules) (decision int, descisionString string, relevantRuleIndex int,results []int,appliedRulesIndices []int) {
 	//
 	// for each message we check its attributes against all of the rules and return a decision
@@ -32,35 +34,26 @@ func Check(message *MessageAttributes, rules *Rules) (decision int, descisionStr
 	N := len(rules.Rules)
 
 	results = make([]int, N)
-	sem := make(chan int, N) // semaphore pattern
-	if true{  // check in parallel
-
-		for i, rule := range (rules.Rules) { // check all the rules in parallel
-			go func(in_i int, in_rule Rule) {
-				results[in_i] = CheckOneRule(message, &in_rule)
-				sem <- 1 // mark that the one rule check is finished
-			}(i, rule)
-
-		}
-
-		// wait for all goroutines to finish
-		for i := 0; i < N; i++ {
-			<-sem
-		}
+	for i := range rules.Rules {
+		results[i] = CheckOneRule(message, &rules.Rules[i])
+	}
 
-	}else{ // used for debugging
+	decision, descisionString, relevantRuleIndex, appliedRulesIndices = combineResults(rules, results)
+	return decision,descisionString,relevantRuleIndex, results, appliedRulesIndices
+}
 
-		for in_i,in_rule := range(rules.Rules) {
-			results[in_i] = CheckOneRule(message, &in_rule)
-		}
-	}
+// combineResults goes over the results of the rules and selects the most restricting decision (by order of precedence).
+// The results of audit rules are not used.
+func combineResults(rules *Rules, results []int) (decision int, descisionString string, relevantRuleIndex int, appliedRulesIndices []int) {
 
-	// go over the results and test by order of precedence
 	appliedRulesIndices = make([]int, 0)
 	relevantRuleIndex = -1
 
 	max_decision := DEFAULT
-	for i := 0; i < N; i++ {
+	for i := 0; i < len(results); i++ {
+		if IsAuditRule(&rules.Rules[i]) {
+			continue
+		}
 		if results[i]>DEFAULT {
 			appliedRulesIndices = append(appliedRulesIndices,i)
 		}
@@ -71,53 +64,157 @@ func Check(message *MessageAttributes, rules *Rules) (decision int, descisionStr
 	}
 	decision = max_decision
 	descisionString = ActionTypeNames[decision]
-	return decision,descisionString,relevantRuleIndex, results, appliedRulesIndices
+	return decision, descisionString, relevantRuleIndex, appliedRulesIndices
+}
+
+// messageResourceTypes returns the resource types of the message. A gRPC message has two resources: the service and the method.
+func messageResourceTypes(message *MessageAttributes) []string {
+	if strings.EqualFold(message.ContextProtocol, "GRPC") {
+		return []string{"grpcService", "grpcMethod"}
+	}
+	return []string{message.ContextType}
+}
+
+// matchingResourceTypes returns the resource types of the message that match the rule's resource type
+func matchingResourceTypes(message *MessageAttributes, rule *Rule) []string {
+	resourceTypes := []string{}
+	for _, t := range messageResourceTypes(message) {
+		if rule.Resource.ResourceTypeRegex.MatchString(t) {
+			resourceTypes = append(resourceTypes, t)
+		}
+	}
+	return resourceTypes
+}
+
+// matchResourceName tests the rule's resource name with the message's resource of each of the matching resource types.
+// It returns the result and the message's resource name (used in the trace).
+func matchResourceName(message *MessageAttributes, rule *Rule, resourceTypes []string) (bool, string) {
+	if len(resourceTypes) == 0 {
+		return false, messageResourceName(message, message.ContextType)
+	}
+	for _, t := range resourceTypes {
+		resourceName := messageResourceName(message, t)
+		if rule.Resource.ResourceNameRegex.MatchString(resourceName) {
+			return true, resourceName
+		}
+	}
+	return false, messageResourceName(message, resourceTypes[0])
+}
+
+// messageResourceName returns the message attribute that is compared with the rule's resource name (of the resource type):
+// the topic or consumer group for KAFKA, the service (package.Service) or the full method (package.Service/Method) for GRPC
+// and the path for other protocols
+func messageResourceName(message *MessageAttributes, resourceType string) string {
+	switch {
+	case strings.EqualFold(message.ContextProtocol, "KAFKA"):
+		if resourceType == "consumerGroup" {
+			return message.KafkaConsumerGroup
+		}
+		return message.KafkaTopic
+	case strings.EqualFold(message.ContextProtocol, "GRPC"):
+		if resourceType == "grpcService" {
+			return message.GrpcService
+		}
+		return message.GrpcService + "/" + message.GrpcMethod
+	}
+	return message.RequestPath
+}
+
+// messageOperation returns the message attribute that is compared with the rule's operation: the method for GRPC and the
+// request method for other protocols
+func messageOperation(message *MessageAttributes) string {
+	if strings.EqualFold(message.ContextProtocol, "GRPC") {
+		return message.GrpcMethod
+	}
+	return message.RequestMethod
 }
 
 // CheckOneRules gives the result of testing the message attributes with of one rule
 func CheckOneRule(message *MessageAttributes, rule *Rule) int {
+	return checkOneRule(message, rule, nil)
+}
+
+// checkOneRule tests the message attributes with one rule. If trace is not nil then all of the stages are tested
+// (so that the trace explains all the reasons of a mismatch) and their results are added to the trace.
+// The conditions are not evaluated after a mismatch of another stage (as in Check, so custom condition functions are called with the same messages).
+func checkOneRule(message *MessageAttributes, rule *Rule, trace *RuleTrace) int {
+	// ----------------------
+	// test the validity period of the rule:
+	allMatch := true
+	if hasValidity(rule) {
+		requestTime, ok := messageTime(message) // rules with a validity period do not apply to messages without a request time
+		match := ok && IsValidAt(rule, requestTime)
+		messageValue := ""
+		if ok {
+			messageValue = requestTime.Format(time.RFC3339)
+		}
+		trace.addStage("validity", match, messageValue, validityPattern(rule))
+		if !match && trace==nil{
+			return DEFAULT
+		}
+		allMatch = match
+	}
+
 	// ----------------------
 	// compare basic message attributes:
 
 	match:=TestSender(rule,message)
-	if !match{
+	trace.addStage("sender", match, senderMessageValue(rule, message), rule.Sender.SenderType+":"+rule.Sender.SenderName)
+	if !match && trace==nil{
 		return DEFAULT
 	}
+	allMatch = allMatch && match
 
 	match=TestReceiver(rule,message)
-	if !match{
+	trace.addStage("receiver", match, receiverMessageValue(rule, message), rule.Receiver.ReceiverType+":"+rule.Receiver.ReceiverName)
+	if !match && trace==nil{
 		return DEFAULT
 	}
+	allMatch = allMatch && match
 
-	match = rule.OperationRegex.Match([]byte(message.RequestMethod)) // supports wildcards
-	if !match{
+	operation := messageOperation(message)
+	match = rule.OperationRegex.Match([]byte(operation)) // supports wildcards
+	trace.addStage("operation", match, operation, rule.Operation)
+	if !match && trace==nil{
 		return DEFAULT
 	}
+	allMatch = allMatch && match
 
 	// ----------------------
 	// compare resource:
-	if rule.Protocol != "*"{
-		if !strings.EqualFold(message.ContextProtocol, rule.Protocol) { // regardless of case // need to support wildcards!
-			return DEFAULT
-		}
+	match = rule.ProtocolRegex.MatchString(message.ContextProtocol) // regardless of case, supports wildcards
+	trace.addStage("protocol", match, message.ContextProtocol, rule.Protocol)
+	if !match && trace==nil{
+		return DEFAULT
+	}
+	allMatch = allMatch && match
 
-		if message.ContextType != rule.Resource.ResourceType { // need to support wildcards?
-			return DEFAULT
-		}
+	resourceTypes := matchingResourceTypes(message, rule) // supports wildcards
+	match = len(resourceTypes) > 0
+	trace.addStage("resourceType", match, message.ContextType, rule.Resource.ResourceType)
+	if !match && trace==nil{
+		return DEFAULT
+	}
+	allMatch = allMatch && match
 
-		match = rule.Resource.ResourceNameRegex.Match([]byte(message.RequestPath)) // supports wildcards
-		if !match {
-			return DEFAULT
-		}
+	match, resourceName := matchResourceName(message, rule, resourceTypes) // supports wildcards
+	trace.addStage("resourceName", match, resourceName, rule.Resource.ResourceName)
+	if !match && trace==nil{
+		return DEFAULT
 	}
+	allMatch = allMatch && match
 
 	// ----------------------
 	// test conditions:
 	conditionsResult := true // if there are no conditions then we skip the test and return the rule.Decision
+	if len(rule.DNFConditions)>0 && !allMatch {
+		trace.addNotEvaluatedStage("conditions")
+		return DEFAULT
+	}
 	if len(rule.DNFConditions)>0{
-		conditionsResult = TestConditions(rule, message)
+		conditionsResult = testConditions(rule, message, trace)
 	}
-	if conditionsResult == false {
+	if conditionsResult == false || !allMatch {
 		return DEFAULT
 	}
 
@@ -149,6 +246,12 @@ func TestSender(rule *Rule, message *MessageAttributes) bool {
 			}
 		case "*", "service":
 			match_temp = expandedSender.Regexp.Match([]byte(message.SourceService)) // supports wildcards
+		case "namespace":
+			match_temp = expandedSender.Regexp.MatchString(message.SourceNamespace) // supports wildcards
+		case "principal":
+			match_temp = expandedSender.Regexp.MatchString(message.SourcePrincipal) // supports wildcards
+		case "labels":
+			match_temp = expandedSender.Selector.Matches(message.SourceLabels)
 		default:
 			panic("type not supported")
 		}
@@ -175,6 +278,12 @@ func TestReceiver(rule *Rule, message *MessageAttributes) bool {
 			}
 		case "*", "service":
 			match_temp = expandedReceiver.Regexp.Match([]byte(message.DestinationService)) // supports wildcards
+		case "namespace":
+			match_temp = expandedReceiver.Regexp.MatchString(message.DestinationNamespace) // supports wildcards
+		case "principal":
+			match_temp = expandedReceiver.Regexp.MatchString(message.DestinationPrincipal) // supports wildcards
+		case "labels":
+			match_temp = expandedReceiver.Selector.Matches(message.DestinationLabels)
 		default:
 			panic("type not supported")
 		}
@@ -188,68 +297,78 @@ func TestReceiver(rule *Rule, message *MessageAttributes) bool {
 }
 // testConditions tests the conditions of the rule with the message attributes
 func TestConditions(rule *Rule, message *MessageAttributes) bool{
+	return testConditions(rule, message, nil)
+}
+
+// testConditions tests the conditions of the rule with the message attributes and adds the results of each ANDConditions clause and each condition to the trace (if not nil)
+func testConditions(rule *Rule, message *MessageAttributes, trace *RuleTrace) bool{
 	//
 	dnfConditions:=rule.DNFConditions
 	res:=make([]bool, len(dnfConditions))
 	for i_andCondtions, andConditions:=range(dnfConditions){
 		temp_res:=true
+		andTrace := ANDConditionsTrace{}
 		for _, condition:=range(andConditions.ANDConditions){ // calculate AND clauses
-			oneConditionResult:=testOneCondition(&condition,message) // test one condition
+			oneConditionResult, messageValue :=testOneCondition(&condition,message) // test one condition
 			temp_res = temp_res && oneConditionResult // logic AND
+			if trace != nil {
+				andTrace.Conditions = append(andTrace.Conditions, newConditionTrace(&condition, messageValue, oneConditionResult))
+			}
 		}
 		res[i_andCondtions] = temp_res
+		if trace != nil {
+			andTrace.Result = temp_res
+			trace.DNFConditions = append(trace.DNFConditions, andTrace)
+		}
 	}
 
 	output := false  // calculate OR of all the AND clauses
 	for _, r := range(res){
 		output = output || r // logic OR
 	}
+	trace.addStage("conditions", output, "", "")
 	return output
 }
 
-// testOneCondition tests one condition of the rule with the message attributes
-func testOneCondition(c *Condition,message *MessageAttributes) bool {
+// testOneCondition tests one condition of the rule with the message attributes. It returns the result and the value extracted from the message (as a string)
+func testOneCondition(c *Condition,message *MessageAttributes) (bool, string) {
 	// ---------------
-	// currently we support the following attributes:
-	// payloadSize
-	// requestUseragent
-	// utcHoursFromMidnight
+	// the condition keywords are in the attribute registry (see attributes.go) except for:
+	// true, false
+	// senderLabel[key], receiverLabel[key]
+	// senderLabels, receiverLabels (label selectors)
+	// custom:<name> (custom condition functions)
 	// ---------------
-	var valueToCompareInt int64
-	var valueToCompareFloat float64
-	var valueToCompareString string
+
+	if c.AttributeIsCustom { // the custom function handles all of the methods
+		return testCustomCondition(c, message)
+	}
+
+	if isExistenceMethod(c.Method) { // EX and NEX are tested the same way for all of the attributes
+		exists, messageValue := attributeExists(c, message)
+		if c.Method == "NEX" || c.Method == "nex" {
+			return !exists, messageValue
+		}
+		return exists, messageValue
+	}
 
 	result:=false
+	messageValue:="" // used in the trace
 	// select type of test by types of attribute and methods
 	switch (c.Attribute){
 	case "true","TRUE":
 		result = true
 	case "false","FALSE":
 		result = false
-	case("payloadSize"):
-		valueToCompareInt = message.RequestSize
-		result = compareIntFunc(valueToCompareInt, c.Method, c.ValueInt)
-	case("requestUseragent"):
-		valueToCompareString = message.RequestUseragent
-		if c.Method == "RE" || c.Method == "re" || c.Method == "NRE" || c.Method == "nre" {
-			result = compareRegexFunc(valueToCompareString, c.Method, c.ValueRegex)
-		}else{
-			result = compareStringWithWildcardsFunc(valueToCompareString, c.Method, c.ValueStringRegex)
-		}
-	case("utcHoursFromMidnight"):
-		valueToCompareFloat = message.RequestTimeHoursFromMidnightUTC
-		result = compareFloatFunc(valueToCompareFloat, c.Method, c.ValueFloat)
-	case("minuteParity"):
-		valueToCompareInt = message.RequestTimeMinutesParity
-		result = compareIntFunc(valueToCompareInt, c.Method, c.ValueInt)
-		fmt.Println("message.RequestTimeMinutesParity=",message.RequestTimeMinutesParity,valueToCompareInt,c.Method, c.ValueInt)
 	case("senderLabel"):
 		if c.AttributeIsSenderLabel==false{
 			panic("senderLabel without the correct format")
 		}
 		if valueToCompareString1,ok := message.SourceLabels[c.AttributeSenderLabelKey]; ok { // enter the block only if the key exists
+			messageValue = valueToCompareString1
 			if c.ValueIsReceiverLabel {
 				if valueToCompareString2,ok2 := message.DestinationLabels[c.ValueReceiverLabelKey];ok2 {
+					messageValue += " (receiverLabel: " + valueToCompareString2 + ")"
 					if c.Method == "RE" || c.Method == "re" || c.Method == "NRE" || c.Method == "nre" {
 						panic("wrong method with comparison of two labels")
 					}
@@ -259,34 +378,78 @@ func testOneCondition(c *Condition,message *MessageAttributes) bool {
 				if c.Method == "RE" || c.Method == "re" || c.Method == "NRE" || c.Method == "nre" {
 					result = compareRegexFunc(valueToCompareString1, c.Method, c.ValueRegex)
 				} else {
-					if c.Method == "EX" || c.Method == "ex" { // just test the existence of the key
-						result = true
-					} else {
-						result = compareStringWithWildcardsFunc(valueToCompareString1, c.Method, c.ValueStringRegex) // string comparison with wildcards
-					}
+					result = compareStringWithWildcardsFunc(valueToCompareString1, c.Method, c.ValueStringRegex) // string comparison with wildcards
 				}
 			}
 		}
+	case "senderLabels", "receiverLabels":
+		result, messageValue = testSelectorCondition(c, message)
 	case("receiverLabel"):
 		if c.AttributeIsReceiverLabel==false{
 			panic("receiverLabel without the correct format")
 		}
 		if valueToCompareString1,ok := message.DestinationLabels[c.AttributeReceiverLabelKey]; ok { // enter the block only if the key exists
+			messageValue = valueToCompareString1
 			if c.Method == "RE" || c.Method == "re" || c.Method == "NRE" || c.Method == "nre" {
 				result = compareRegexFunc(valueToCompareString1, c.Method, c.ValueRegex)
 			} else {
-				if c.Method == "EX" || c.Method == "ex" { // just test the existence of the key
-					result = true
-				} else {
-					result = compareStringWithWildcardsFunc(valueToCompareString1, c.Method, c.ValueStringRegex) // compare strings with wildcards
-				}
+				result = compareStringWithWildcardsFunc(valueToCompareString1, c.Method, c.ValueStringRegex) // compare strings with wildcards
 			}
 		}
 
 	default:
+		result, messageValue = conditionAttribute(c).test(c, message)
+	}
+	return result, messageValue
+}
+
+// attributeExists tests the existence of the condition's attribute in the message (used by the EX and NEX methods). It returns the result and the value extracted from the message (as a string).
+// An attribute does not exist in the message when:
+// - string attributes: the string is empty
+// - int attributes: the value is zero (the yaml field is omitted)
+// - time attributes (extracted from the request time): the message has no request time
+// - labels: the label key is not in the message's labels
+// - label selectors (senderLabels, receiverLabels): the message has no labels
+// (the extractors in the attribute registry return the existence of the other attributes)
+func attributeExists(c *Condition,message *MessageAttributes) (bool, string) {
+	switch (c.Attribute){
+	case "true","TRUE","false","FALSE":
+		return true, ""
+	case("senderLabel"):
+		value, ok := message.SourceLabels[c.AttributeSenderLabelKey]
+		return ok, value
+	case("receiverLabel"):
+		value, ok := message.DestinationLabels[c.AttributeReceiverLabelKey]
+		return ok, value
+	case "senderLabels":
+		return len(message.SourceLabels) > 0, formatLabels(message.SourceLabels)
+	case "receiverLabels":
+		return len(message.DestinationLabels) > 0, formatLabels(message.DestinationLabels)
+	default:
+		messageValue, exists := conditionAttribute(c).extract(message)
+		return exists, messageValue
+	}
+}
+
+// conditionAttribute returns the registered attribute of the condition's keyword
+func conditionAttribute(c *Condition) *Attribute {
+	if c.attribute != nil { // resolved when the rules were read
+		return c.attribute
+	}
+	attribute := lookupAttribute(c.Attribute)
+	if attribute == nil {
 		panic("condition keyword not supported")
 	}
-	return result
+	return attribute
+}
+
+// isExistenceMethod returns true for the EX and NEX methods
+func isExistenceMethod(method string) bool {
+	switch(method){
+	case "EX","ex","NEX","nex":
+		return true
+	}
+	return false
 }
 
 // compareIntFunc compares one int value according the method string.
@@ -294,7 +457,7 @@ func compareIntFunc(value1 int64, method string ,value2 int64) bool{ //value2 is
 	switch(method){
 	case "EQ","eq":
 		return(value1==value2)
-	case "NEQ","neq":
+	case "NEQ","neq","NE","ne":
 		return(value1!=value2)
 	case "LE","le":
 		return(value1<=value2)
@@ -312,7 +475,7 @@ func compareFloatFunc(value1 float64, method string ,value2 float64) bool{ //val
 	switch(method){
 	case "EQ","eq":
 		return(value1==value2)
-	case "NEQ","neq":
+	case "NEQ","neq","NE","ne":
 		return(value1!=value2)
 	case "LE","le":
 		return(value1<=value2)
@@ -330,7 +493,7 @@ func compareStringFunc(value1 string, method string ,value2 string) bool{
 	switch(method){
 	case "EQ","eq":
 		return(value1==value2)
-	case "NEQ","neq":
+	case "NEQ","neq","NE","ne":
 		return(value1!=value2)
 	}
 	return false
@@ -338,14 +501,84 @@ func compareStringFunc(value1 string, method string ,value2 string) bool{
 // compareStringWithWildcardsFunc compares one string value according the method string (supports wildcards)
 func compareStringWithWildcardsFunc(value1 string, method string ,value2 *regexp.Regexp) bool{
 	switch(method){
-	case "EQ","eq":
+	case "EQ","eq","IN","in": // the value may be a list of strings separated by ';'
 		return (value2.MatchString(value1))
-	case "NEQ","neq":
+	case "NEQ","neq","NE","ne","NIN","nin":
 		return !(value2.MatchString(value1))
 	}
 	return false
 
 }
+// compareIntListFunc tests whether the value is in the list (IN, NIN) or in the range (BETWEEN, NBETWEEN, inclusive)
+func compareIntListFunc(value1 int64, method string, value2 []int64) bool { //value2 is the reference list from the rule
+	switch method {
+	case "IN", "in", "NIN", "nin":
+		in := false
+		for _, v := range value2 {
+			if value1 == v {
+				in = true
+				break
+			}
+		}
+		return in == (method == "IN" || method == "in")
+	case "BETWEEN", "between":
+		return len(value2) == 2 && value1 >= value2[0] && value1 <= value2[1]
+	case "NBETWEEN", "nbetween":
+		return len(value2) == 2 && (value1 < value2[0] || value1 > value2[1])
+	}
+	return false
+}
+// compareFloatListFunc tests whether the value is in the list (IN, NIN) or in the range (BETWEEN, NBETWEEN, inclusive)
+func compareFloatListFunc(value1 float64, method string, value2 []float64) bool { //value2 is the reference list from the rule
+	switch method {
+	case "IN", "in", "NIN", "nin":
+		in := false
+		for _, v := range value2 {
+			if value1 == v {
+				in = true
+				break
+			}
+		}
+		return in == (method == "IN" || method == "in")
+	case "BETWEEN", "between":
+		return len(value2) == 2 && value1 >= value2[0] && value1 <= value2[1]
+	case "NBETWEEN", "nbetween":
+		return len(value2) == 2 && (value1 < value2[0] || value1 > value2[1])
+	}
+	return false
+}
+// compareIPFunc compares one IP value according the method string.
+func compareIPFunc(value1 net.IP, method string ,value2 net.IP) bool{ //value2 is the reference value from the rule
+	switch(method){
+	case "EQ","eq":
+		return value1.Equal(value2)
+	case "NEQ","neq","NE","ne":
+		return !value1.Equal(value2)
+	}
+	return false
+}
+// compareCIDRFunc tests whether the ip is in one of the networks (IN_CIDR) or in none of them (NOT_IN_CIDR).
+// As with time attributes, both methods are false for a message without the ip (an allow rule with NOT_IN_CIDR does not apply to it).
+func compareCIDRFunc(value1 net.IP, method string, value2 []*net.IPNet) bool { //value2 is the reference value from the rule
+	if value1 == nil {
+		return false
+	}
+	in := false
+	for _, cidr := range value2 {
+		if cidr.Contains(value1) {
+			in = true
+			break
+		}
+	}
+	switch method {
+	case "IN_CIDR", "in_cidr":
+		return in
+	case "NOT_IN_CIDR", "not_in_cidr":
+		return !in
+	}
+	return false
+}
+
 // compareRegexFunc compares one string value according the regular expression string.
 func compareRegexFunc(value1 string, method string ,value2 *regexp.Regexp) bool{ //value2 is the reference value from the rule
 	switch(method){
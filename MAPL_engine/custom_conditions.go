@@ -0,0 +1,87 @@
+package MAPL_engine
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ConditionFunc is a custom condition function. It is called with the message attributes and the condition
+// (the condition's method and value may be used as parameters of the function).
+type ConditionFunc func(message *MessageAttributes, condition Condition) (bool, error)
+
+// the attribute of a condition with a custom function is "custom:<name>"
+const customAttributePrefix = "custom:"
+
+var (
+	ErrInvalidConditionFunc = errors.New("invalid custom condition function")
+	ErrUnknownConditionFunc = errors.New("custom condition function is not registered")
+)
+
+var conditionFuncRegistry = struct {
+	sync.RWMutex
+	funcs map[string]ConditionFunc
+}{funcs: make(map[string]ConditionFunc)}
+
+// RegisterConditionFunc registers a custom condition function. Rules use it in their conditions with the attribute "custom:<name>".
+// The function must be registered before the rules that use it are read (rules with unknown functions are rejected).
+// If the function returns an error then the condition is false.
+func RegisterConditionFunc(name string, fn ConditionFunc) error {
+	if name == "" || strings.ContainsAny(name, "[]: ") || fn == nil {
+		return fmt.Errorf("%w: %q", ErrInvalidConditionFunc, name)
+	}
+	conditionFuncRegistry.Lock()
+	defer conditionFuncRegistry.Unlock()
+	if _, ok := conditionFuncRegistry.funcs[name]; ok {
+		return fmt.Errorf("%w: %q is already registered", ErrInvalidConditionFunc, name)
+	}
+	conditionFuncRegistry.funcs[name] = fn
+	return nil
+}
+
+// UnregisterConditionFunc removes a custom condition function from the registry. Rules that were already read keep using it.
+func UnregisterConditionFunc(name string) {
+	conditionFuncRegistry.Lock()
+	defer conditionFuncRegistry.Unlock()
+	delete(conditionFuncRegistry.funcs, name)
+}
+
+func lookupConditionFunc(name string) ConditionFunc {
+	conditionFuncRegistry.RLock()
+	defer conditionFuncRegistry.RUnlock()
+	return conditionFuncRegistry.funcs[name]
+}
+
+// convertCustomCondition resolves the custom function of the condition (if the attribute is "custom:<name>")
+func convertCustomCondition(c *Condition) error {
+	if !strings.HasPrefix(c.Attribute, customAttributePrefix) {
+		return nil
+	}
+	name := strings.TrimPrefix(c.Attribute, customAttributePrefix)
+	c.customFunc = lookupConditionFunc(name)
+	if c.customFunc == nil {
+		return &fieldError{"attribute", fmt.Errorf("%w: %q", ErrUnknownConditionFunc, name)}
+	}
+	c.AttributeIsCustom = true
+	c.AttributeCustomFuncName = name
+	return nil
+}
+
+// testCustomCondition calls the custom function of the condition. It returns the result and the error (as a string, used in the trace).
+// The condition is false if the function is not registered (for example for rules that were restored from json or bson and not converted
+// after the function was unregistered).
+func testCustomCondition(c *Condition, message *MessageAttributes) (bool, string) {
+	fn := c.customFunc
+	if fn == nil { // the rule was not converted when read
+		fn = lookupConditionFunc(c.AttributeCustomFuncName)
+		if fn == nil {
+			return false, fmt.Sprintf("error: %v: %q", ErrUnknownConditionFunc, c.AttributeCustomFuncName)
+		}
+	}
+	result, err := fn(message, *c)
+	if err != nil {
+		return false, "error: " + err.Error()
+	}
+	return result, ""
+}
@@ -0,0 +1,139 @@
+package MAPL_engine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// tenantMatch is the custom condition function of the documentation: the tenant (the first part of the path) is the namespace of the sender
+func tenantMatch(m *MessageAttributes, c Condition) (bool, error) {
+	parts := strings.Split(m.RequestPath, "/")
+	if len(parts) < 3 {
+		return false, fmt.Errorf("no tenant in path %q", m.RequestPath)
+	}
+	return parts[1] == m.SourceNamespace, nil
+}
+
+// customRules returns the rules of strictRules (with any path) with a condition of the custom function
+func customRules(name string) string {
+	rules := strings.Replace(strictRules, `resourceName: "/books/*"`, `resourceName: "/*"`, 1)
+	return strings.Replace(rules, "    decision: allow", `    DNFconditions:
+      - ANDconditions:
+        - attribute: custom:`+name+`
+          method: EQ
+          value: true
+    decision: allow`, 1)
+}
+
+func customMessage(path string) *MessageAttributes {
+	return &MessageAttributes{SourceService: "A.my_namespace", SourceNamespace: "my_namespace", DestinationService: "B.my_namespace",
+		ContextProtocol: "http", ContextType: "httpPath", RequestPath: path, RequestMethod: "GET"}
+}
+
+func TestRegisterConditionFunc(t *testing.T) {
+	if err := RegisterConditionFunc("testTenantMatch", tenantMatch); err != nil {
+		t.Fatal(err)
+	}
+	defer UnregisterConditionFunc("testTenantMatch")
+
+	tests := []struct {
+		name string
+		fn   ConditionFunc
+	}{
+		{"testTenantMatch", tenantMatch}, // already registered
+		{"", tenantMatch},
+		{"a:b", tenantMatch},
+		{"a b", tenantMatch},
+		{"a[0]", tenantMatch},
+		{"testNil", nil},
+	}
+	for _, test := range tests {
+		if err := RegisterConditionFunc(test.name, test.fn); !errors.Is(err, ErrInvalidConditionFunc) {
+			t.Errorf("%q: expected an invalid function error, got %v", test.name, err)
+		}
+	}
+
+	_, err := ParseRules([]byte(customRules("testUnknown")))
+	ruleErr, ok := err.(*RuleError)
+	if !ok || !errors.Is(err, ErrUnknownConditionFunc) || ruleErr.Path != "rules[0].DNFconditions[0].ANDconditions[0].attribute" || ruleErr.Line != 16 || ruleErr.Column != 22 {
+		t.Errorf("expected an unknown function error, got %v", err)
+	}
+}
+
+func TestCustomCondition(t *testing.T) {
+	if err := RegisterConditionFunc("testTenantMatch2", tenantMatch); err != nil {
+		t.Fatal(err)
+	}
+	rules, err := ParseRules([]byte(customRules("testTenantMatch2")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path         string
+		decision     int
+		messageValue string
+	}{
+		{"/my_namespace/books", ALLOW, ""},
+		{"/other/books", DEFAULT, ""},
+		{"/books", DEFAULT, "error: no tenant in path \"/books\""}, // the function returns an error
+	}
+	for _, test := range tests {
+		decision, _, _, _, _, trace := CheckWithTrace(customMessage(test.path), rules)
+		if decision != test.decision {
+			t.Errorf("%v: expected decision %v, got %v", test.path, test.decision, decision)
+		}
+		if messageValue := trace.Rules[0].DNFConditions[0].Conditions[0].MessageValue; messageValue != test.messageValue {
+			t.Errorf("%v: unexpected message value %q", test.path, messageValue)
+		}
+	}
+
+	// rules restored from json do not have the function. They use the registry, and the condition is false after the function is unregistered.
+	data, err := json.Marshal(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var restored Rules
+	if err := json.Unmarshal(data, &restored); err != nil {
+		t.Fatal(err)
+	}
+	if decision, _, _, _, _ := Check(customMessage("/my_namespace/books"), &restored); decision != ALLOW {
+		t.Errorf("expected allow for the restored rules, got %v", decision)
+	}
+	UnregisterConditionFunc("testTenantMatch2")
+	if decision, _, _, _, _ := Check(customMessage("/my_namespace/books"), rules); decision != ALLOW {
+		t.Errorf("the rules that were read should keep the function, got %v", decision)
+	}
+	decision, _, _, _, _, trace := CheckWithTrace(customMessage("/my_namespace/books"), &restored)
+	if decision != DEFAULT || !strings.Contains(trace.Rules[0].DNFConditions[0].Conditions[0].MessageValue, ErrUnknownConditionFunc.Error()) {
+		t.Errorf("expected a false condition of the unregistered function, got %v: %+v", decision, trace.Rules[0].DNFConditions)
+	}
+}
+
+func ExampleRegisterConditionFunc() {
+	err := RegisterConditionFunc("tenantMatch", func(m *MessageAttributes, c Condition) (bool, error) {
+		parts := strings.Split(m.RequestPath, "/")
+		if len(parts) < 3 {
+			return false, fmt.Errorf("no tenant in path %q", m.RequestPath)
+		}
+		return parts[1] == m.SourceNamespace, nil
+	})
+	if err != nil {
+		panic(err)
+	}
+	defer UnregisterConditionFunc("tenantMatch")
+
+	rules, err := ParseRules([]byte(customRules("tenantMatch")))
+	if err != nil {
+		panic(err)
+	}
+	for _, path := range []string{"/my_namespace/books", "/other/books"} {
+		_, decisionString, _, _, _ := Check(customMessage(path), rules)
+		fmt.Println(path, decisionString)
+	}
+	// Output:
+	// /my_namespace/books allow
+	// /other/books rules do not apply to message - block by default
+}
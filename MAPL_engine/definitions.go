@@ -8,12 +8,12 @@ import (
 )
 
 type GeneralStruct interface { // a general interface to structures.
-	ToJson() string // This function is used when comparing structures read from yaml files to the resulting fields in the structure.
+	ToJson() string
 }
 
 //-------------------rules-------------------------------------
 type Sender struct {
-	// if SenderName is a list (example: "srv1,srv2,srv123") then it is assumed that all are of the same type
+	// if SenderName is a list (example: "srv1;srv2;srv123") then it is assumed that all are of the same type
 	SenderName string `yaml:"senderName,omitempty" json:"SenderName,omitempty" bson:"SenderName" structs:"SenderName,omitempty"`
 	SenderType string `yaml:"senderType,omitempty" json:"SenderType,omitempty" bson:"SenderType,omitempty" structs:"SenderType,omitempty"`
 	SenderList []ExpandedSenderReceiver `yaml:"-" json:"SenderList,omitempty" bson:"SenderList,omitempty" structs:"SenderList,omitempty"`
@@ -37,6 +37,7 @@ type Resource struct {
 	ResourceType     string `yaml:"resourceType,omitempty" json:"ResourceType,omitempty" bson:"ResourceType,omitempty" structs:"ResourceType,omitempty"`
 	ResourceName     string `yaml:"resourceName,omitempty" json:"ResourceName,omitempty" bson:"ResourceName,omitempty" structs:"ResourceName,omitempty"`
 	ResourceNameRegex *regexp.Regexp `yaml:"-" json:"ResourceNameRegex,omitempty" bson:"ResourceNameRegex,omitempty" structs:"ResourceNameRegex,omitempty"`
+	ResourceTypeRegex *regexp.Regexp `yaml:"-" json:"ResourceTypeRegex,omitempty" bson:"ResourceTypeRegex,omitempty" structs:"ResourceTypeRegex,omitempty"`
 }
 // Condition structure - part of the rule as defined in MAPL (https://github.com/octarinesec/MAPL/tree/master/docs/MAPL_SPEC.md)
 type Condition struct {
@@ -47,6 +48,14 @@ type Condition struct {
 	ValueFloat float64 `yaml:"-" json:"ValueFloat,omitempty" bson:"ValueFloat,omitempty" structs:"ValueFloat,omitempty"`
 	ValueRegex *regexp.Regexp `yaml:"-" json:"ValueRegex,omitempty" bson:"ValueRegex,omitempty" structs:"ValueRegex,omitempty"`
 	ValueStringRegex *regexp.Regexp `yaml:"-" json:"ValueStringRegex,omitempty" bson:"ValueStringRegex,omitempty" structs:"ValueStringRegex,omitempty"`
+	ValueDuration time.Duration `yaml:"-" json:"ValueDuration,omitempty" bson:"ValueDuration,omitempty" structs:"ValueDuration,omitempty"`
+	ValueIP net.IP `yaml:"-" json:"ValueIP,omitempty" bson:"ValueIP,omitempty" structs:"ValueIP,omitempty"`
+	ValueIntList []int64 `yaml:"-" json:"ValueIntList,omitempty" bson:"ValueIntList,omitempty" structs:"ValueIntList,omitempty"` // the list of the IN and NIN methods or the range of the BETWEEN and NBETWEEN methods (int and duration attributes)
+	ValueFloatList []float64 `yaml:"-" json:"ValueFloatList,omitempty" bson:"ValueFloatList,omitempty" structs:"ValueFloatList,omitempty"` // the list of the IN and NIN methods or the range of the BETWEEN and NBETWEEN methods (float attributes)
+	ValueCIDRs []*net.IPNet `yaml:"-" json:"ValueCIDRs,omitempty" bson:"ValueCIDRs,omitempty" structs:"ValueCIDRs,omitempty"` // the list of networks of the IN_CIDR and NOT_IN_CIDR methods
+	ValueTime time.Time `yaml:"-" json:"ValueTime,omitempty" bson:"ValueTime,omitempty" structs:"ValueTime,omitempty"` // start of the time value (a timestamp or a date)
+	ValueTimeEnd time.Time `yaml:"-" json:"ValueTimeEnd,omitempty" bson:"ValueTimeEnd,omitempty" structs:"ValueTimeEnd,omitempty"` // end (exclusive) of the time value
+	ValueSelector *LabelSelector `yaml:"-" json:"ValueSelector,omitempty" bson:"ValueSelector,omitempty" structs:"ValueSelector,omitempty"` // the label selector of the SELECTOR and NSELECTOR methods (senderLabels, receiverLabels)
 
 	AttributeIsSenderLabel bool `yaml:"-" json:"AttributeIsSenderLabel,omitempty" bson:"AttributeIsSenderLabel,omitempty" structs:"AttributeIsSenderLabel,omitempty"`
 	AttributeSenderLabelKey string `yaml:"-" json:"AttributeSenderLabelKey,omitempty" bson:"AttributeSenderLabelKey,omitempty" structs:"AttributeSenderLabelKey,omitempty"`
@@ -58,6 +67,12 @@ type Condition struct {
 	OriginalAttribute string `yaml:"-" json:"OriginalAttribute,omitempty" bson:"OriginalAttribute,omitempty" structs:"OriginalAttribute,omitempty"` // used in hash
 	OriginalValue     string `yaml:"-" json:"OriginalValue,omitempty" bson:"OriginalValue,omitempty" structs:"OriginalValue,omitempty"` // used in hash
 
+	AttributeIsCustom bool `yaml:"-" json:"AttributeIsCustom,omitempty" bson:"AttributeIsCustom,omitempty" structs:"AttributeIsCustom,omitempty"`
+	AttributeCustomFuncName string `yaml:"-" json:"AttributeCustomFuncName,omitempty" bson:"AttributeCustomFuncName,omitempty" structs:"AttributeCustomFuncName,omitempty"`
+
+	attribute *Attribute // the registered attribute of the condition keyword (resolved when the rules are read)
+	schedule timeSchedule // the recurring window or cron expression of the IN_WINDOW and CRON methods
+	customFunc ConditionFunc // the registered custom function of a "custom:<name>" attribute (resolved when the rules are read)
 }
 
 // ANDConditions structure - part of the rule as defined in MAPL (https://github.com/octarinesec/MAPL/tree/master/docs/MAPL_SPEC.md)
@@ -70,6 +85,11 @@ type Rule struct {
 	//	<sender, receiver, resource, operation> : <conditions> : <decision>
 	//
 	RuleID        string          `yaml:"rule_id,omitempty" json:"RuleID,omitempty" bson:"RuleID,omitempty" structs:"RuleID,omitempty"`
+	Description   string          `yaml:"description,omitempty" json:"Description,omitempty" bson:"Description,omitempty" structs:"Description,omitempty"`
+	Owner         string          `yaml:"owner,omitempty" json:"Owner,omitempty" bson:"Owner,omitempty" structs:"Owner,omitempty"`
+	Tags          []string        `yaml:"tags,omitempty" json:"Tags,omitempty" bson:"Tags,omitempty" structs:"Tags,omitempty"`
+	ValidFrom     string          `yaml:"validFrom,omitempty" json:"ValidFrom,omitempty" bson:"ValidFrom,omitempty" structs:"ValidFrom,omitempty"` // the rule applies to messages from this timestamp or date (see ValidFromTime)
+	ValidUntil    string          `yaml:"validUntil,omitempty" json:"ValidUntil,omitempty" bson:"ValidUntil,omitempty" structs:"ValidUntil,omitempty"` // the rule applies to messages until this timestamp or date (inclusive)
 	Sender        Sender          `yaml:"sender,omitempty" json:"Sender,omitempty" bson:"Sender" structs:"Sender,omitempty"`
 	Receiver      Receiver        `yaml:"receiver,omitempty" json:"Receiver,omitempty" bson:"Receiver" structs:"Receiver,omitempty"`
 	Protocol      string          `yaml:"protocol,omitempty" json:"Protocol,omitempty" bson:"Protocol" structs:"Protocol,omitempty"`
@@ -77,13 +97,18 @@ type Rule struct {
 	Operation     string          `yaml:"operation,omitempty" json:"Operation,omitempty" bson:"Operation" structs:"Operation,omitempty"`
 	DNFConditions []ANDConditions `yaml:"DNFconditions,omitempty" json:"DNFConditions,omitempty" bson:"DNFConditions,omitempty" structs:"DNFConditions,omitempty"`
 	Decision      string          `yaml:"decision,omitempty" json:"Decision,omitempty" bson:"Decision" structs:"Decision,omitempty"`
+	Mode          string          `yaml:"mode,omitempty" json:"Mode,omitempty" bson:"Mode,omitempty" structs:"Mode,omitempty"` // enforce (the default) or audit (see CheckWithAudit)
 
 	OperationRegex *regexp.Regexp `yaml:"-" json:"OperationRegex,omitempty" bson:"OperationRegex,omitempty" structs:"OperationRegex,omitempty"`
+	ProtocolRegex *regexp.Regexp `yaml:"-" json:"ProtocolRegex,omitempty" bson:"ProtocolRegex,omitempty" structs:"ProtocolRegex,omitempty"`
+	ValidFromTime time.Time `yaml:"-" json:"ValidFromTime,omitempty" bson:"ValidFromTime,omitempty" structs:"ValidFromTime,omitempty"` // start of the validity period (zero if the rule has no validFrom)
+	ValidUntilTime time.Time `yaml:"-" json:"ValidUntilTime,omitempty" bson:"ValidUntilTime,omitempty" structs:"ValidUntilTime,omitempty"` // end (exclusive) of the validity period (zero if the rule has no validUntil)
 
 }
 // Rules structure contains a list of rules
 type Rules struct {
-	Rules []Rule `yaml:"rules,omitempty"`
+	Version string `yaml:"version,omitempty"` // the version of the rules format as written in the rules file (CurrentRulesVersion for rules upgraded from version 1)
+	Rules   []Rule `yaml:"rules,omitempty"`
 }
 //
 type ExpandedSenderReceiver struct {
@@ -94,6 +119,7 @@ type ExpandedSenderReceiver struct {
 	IsCIDR bool `yaml:"-" json:"IsCIDR,omitempty" bson:"IsCIDR,omitempty"`
 	CIDR net.IPNet `yaml:"-" json:"CIDR,omitempty"  bson:"CIDR,omitempty"`
 	IP net.IP `yaml:"-" json:"IP,omitempty" bson:"IP,omitempty"`
+	Selector *LabelSelector `yaml:"-" json:"Selector,omitempty" bson:"Selector,omitempty"` // used with type "labels"
 }
 
 // ToJson converts a structure into a json string
@@ -170,6 +196,8 @@ type MessageAttributes struct {
 	// -----------------------------------------------
 	// The following are general attributes:
 	MessageID string `yaml:"message_id,omitempty"`
+	ExpectedDecision string `yaml:"expected_decision,omitempty"` // the expected decision for the message (allow, alert, block or default). used by CheckExpectations
+	ExpectedRuleID string `yaml:"expected_rule_id,omitempty"` // the expected rule_id of the decision. used by CheckExpectations
 
 	SourceService string  `yaml:"sender_service,omitempty"`//  The service identifier
 	DestinationService    string  `yaml:"receiver_service,omitempty"`//  The fully qualified name of the service that the server belongs to.my-svc.my-namespace
@@ -182,10 +210,17 @@ type MessageAttributes struct {
 	// for ContextProtocol HTTP  ContextType=httpPath
 	// for ContextProtocol=KAFKA  ContextType=kafkaTopic or consumerGroup
 
+	GrpcService string `yaml:"-"` // the gRPC service extracted from the RequestPath. example: payments.PaymentService
+	GrpcMethod string `yaml:"-"` // the gRPC method extracted from the RequestPath. example: Authorize
+
+	KafkaTopic string `yaml:"kafka_topic,omitempty"` // the kafka topic (the resource name of messages with ContextType=kafkaTopic)
+	KafkaConsumerGroup string `yaml:"kafka_consumer_group,omitempty"` // the kafka consumer group (the resource name of messages with ContextType=consumerGroup)
+
 	RequestTimeSecondsFromMidnightUTC float64 `yaml:"-"` // conversion of RequestTime timestamp
 	RequestTimeMinutesFromMidnightUTC float64 `yaml:"-"` // conversion of RequestTime timestamp
 	RequestTimeHoursFromMidnightUTC float64 `yaml:"-"` // conversion of RequestTime timestamp
 	RequestTimeMinutesParity int64 `yaml:"-"` // conversion of RequestTime timestamp // used in istio demo condition
+	RequestTimeParsed time.Time `yaml:"-"` // conversion of RequestTime timestamp
 
 	SourceNetIp net.IP `yaml:"-"`
 	DestinationNetIp net.IP `yaml:"-"`
@@ -204,7 +239,7 @@ type Messages struct {
 func (messageAttributes MessageAttributes) ToJson() string { // method of GeneralStruct interface
 	jsonBytes, err := json.MarshalIndent(messageAttributes, "", "  ")
 	if err != nil {
-		panic("error converting to json in isNumberOfFieldsEqual")
+		panic("error converting to json")
 	}
 	return (string(jsonBytes))
 }
@@ -213,7 +248,7 @@ func (messageAttributes MessageAttributes) ToJson() string { // method of Genera
 func (messages Messages) ToJson() string { // method of GeneralStruct interface
 	jsonBytes, err := json.MarshalIndent(messages, "", "  ")
 	if err != nil {
-		panic("error converting to json in isNumberOfFieldsEqual")
+		panic("error converting to json")
 	}
 	return (string(jsonBytes))
 }
\ No newline at end of file
@@ -0,0 +1,103 @@
+package MAPL_engine
+
+import (
+	"errors"
+	"fmt"
+)
+
+// errors returned (wrapped in RuleError or MessageError) by the error-returning parse functions
+var (
+	ErrInvalidYaml          = errors.New("invalid yaml")
+	ErrInvalidJson          = errors.New("invalid json")
+	ErrUnknownField         = errors.New("unknown field")
+	ErrNotIPOrCIDR          = errors.New("type is 'subnet' but value is not an IP or CIDR")
+	ErrInvalidPattern       = errors.New("value could not be converted to regex")
+	ErrInvalidLabel         = errors.New("label has a wrong format")
+	ErrInvalidTime          = errors.New("error in parsing message time")
+	ErrInvalidLabelsJson    = errors.New("error in parsing labels")
+	ErrUnsupportedAttribute = errors.New("condition keyword not supported")
+	ErrUnsupportedMethod    = errors.New("method not supported for the condition keyword")
+	ErrInvalidValue         = errors.New("invalid condition value")
+	ErrUnsupportedType      = errors.New("sender or receiver type not supported")
+	ErrInvalidDecision      = errors.New("invalid decision")
+	ErrUnsupportedVersion   = errors.New("rules version not supported")
+	ErrNoBundleFiles        = errors.New("no rules files")
+	ErrDuplicateRuleID      = errors.New("duplicate rule_id")
+	ErrInvalidValidity      = errors.New("invalid validity period")
+	ErrInvalidMode          = errors.New("invalid rule mode")
+	ErrMultipleDocuments    = errors.New("several yaml documents")
+)
+
+// RuleError describes a problem in one of the rules. It is returned by ParseRules and ParseRulesFromFile.
+type RuleError struct {
+	RuleIndex int    // index of the rule in the rules list (-1 if the error is not related to a specific rule)
+	RuleID    string // rule_id of the rule
+	Path      string // path to the field. example: rules[3].DNFconditions[0].ANDconditions[1].value
+	Line      int    // line in the yaml input (0 if unknown)
+	Column    int    // column in the yaml input (0 if unknown)
+	Err       error
+}
+
+func (e *RuleError) Error() string {
+	return formatParseError(e.Path, "rule_id", e.RuleIndex, e.RuleID, e.Line, e.Column, e.Err)
+}
+
+func (e *RuleError) Unwrap() error {
+	return e.Err
+}
+
+// MessageError describes a problem in one of the messages. It is returned by ParseMessages and ParseMessagesFromFile.
+type MessageError struct {
+	MessageIndex int    // index of the message in the messages list (-1 if the error is not related to a specific message)
+	MessageID    string // message_id of the message
+	Path         string // path to the field. example: messages[2].request_time
+	Line         int    // line in the yaml input (0 if unknown)
+	Column       int    // column in the yaml input (0 if unknown)
+	Err          error
+}
+
+func (e *MessageError) Error() string {
+	return formatParseError(e.Path, "message_id", e.MessageIndex, e.MessageID, e.Line, e.Column, e.Err)
+}
+
+func (e *MessageError) Unwrap() error {
+	return e.Err
+}
+
+// fieldError is used by the conversion functions to return the path of the field (relative to the rule or message) together with the error
+type fieldError struct {
+	path string
+	err  error
+}
+
+func (e *fieldError) Error() string {
+	return e.path + ": " + e.err.Error()
+}
+
+func (e *fieldError) Unwrap() error {
+	return e.err
+}
+
+func formatParseError(path, idName string, index int, id string, line, column int, err error) string {
+	str := path
+	if str == "" {
+		str = "input"
+	}
+	details := ""
+	if index >= 0 && id != "" {
+		details = idName + ": " + id
+	}
+	if line > 0 {
+		if details != "" {
+			details += ", "
+		}
+		details += fmt.Sprintf("line %v", line)
+		if column > 0 {
+			details += fmt.Sprintf(", column %v", column)
+		}
+	}
+	if details != "" {
+		str += " (" + details + ")"
+	}
+	return str + ": " + err.Error()
+}
@@ -0,0 +1,91 @@
+package MAPL_engine
+
+import (
+	"testing"
+)
+
+// examplePairs are the rules and messages files in the examples folder. Each message has an expected_decision (and an expected_rule_id).
+var examplePairs = [][2]string{
+	{"../examples/rules_basic.yaml", "../examples/messages_basic_sender_name.yaml"},
+	{"../examples/rules_basic.yaml", "../examples/messages_basic_receiver_name.yaml"},
+	{"../examples/rules_sender_with_wildcards.yaml", "../examples/messages_sender_name_test_with_wildcards.yaml"},
+	{"../examples/rules_receiver_with_wildcards.yaml", "../examples/messages_receiver_name_test_with_wildcards.yaml"},
+	{"../examples/rules_sender_list.yaml", "../examples/messages_sender_test_with_lists.yaml"},
+	{"../examples/rules_receiver_list.yaml", "../examples/messages_receiver_test_with_lists.yaml"},
+	{"../examples/rules_resources.yaml", "../examples/messages_resources.yaml"},
+	{"../examples/rules_resources_with_wildcards.yaml", "../examples/messages_resources_test_with_wildcards.yaml"},
+	{"../examples/rules_resource_lists.yaml", "../examples/messages_resources_test_with_lists.yaml"},
+	{"../examples/rules_operations.yaml", "../examples/messages_operations.yaml"},
+	{"../examples/rules_operation_list.yaml", "../examples/messages_operations_test_with_list.yaml"},
+	{"../examples/rules_with_conditions.yaml", "../examples/messages_test_with_conditions.yaml"},
+	{"../examples/rules_existence.yaml", "../examples/messages_existence.yaml"},
+	{"../examples/rules_time_windows.yaml", "../examples/messages_time_windows.yaml"},
+	{"../examples/rules_cidr_conditions.yaml", "../examples/messages_cidr_conditions.yaml"},
+	{"../examples/rules_sets_and_ranges.yaml", "../examples/messages_sets_and_ranges.yaml"},
+	{"../examples/rules_kafka.yaml", "../examples/messages_kafka.yaml"},
+	{"../examples/rules_grpc.yaml", "../examples/messages_grpc.yaml"},
+	{"../examples/rules_protocol_patterns.yaml", "../examples/messages_protocol_patterns.yaml"},
+	{"../examples/rules_sender_receiver_types.yaml", "../examples/messages_sender_receiver_types.yaml"},
+	{"../examples/rules_metadata.yaml", "../examples/messages_metadata.yaml"},
+	{"../examples/rules_audit.yaml", "../examples/messages_audit.yaml"},
+	{"../examples/rules_label_selectors.yaml", "../examples/messages_label_selectors.yaml"},
+	{"../examples/rules_istio.yaml", "../examples/messages_istio.yaml"},
+}
+
+// TestExamples checks the messages of each pair of example files with the rules and compares the decisions with the expected decisions
+func TestExamples(t *testing.T) {
+	for _, pair := range examplePairs {
+		rulesFilename, messagesFilename := pair[0], pair[1]
+		t.Run(messagesFilename[len("../examples/"):], func(t *testing.T) {
+			rules, err := ParseRulesFromFile(rulesFilename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			messages, err := ParseMessagesFromFile(messagesFilename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			report := CheckExpectations(messages, rules)
+			if report.Skipped > 0 {
+				t.Errorf("%v messages without expected_decision", report.Skipped)
+			}
+			for _, result := range report.Results {
+				if !result.Pass {
+					t.Errorf("message #%v: %v", result.MessageIndex, result.Diff)
+				}
+			}
+		})
+	}
+}
+
+// TestCheckExpectationsFailure tests that a wrong expected decision or rule id is reported
+func TestCheckExpectationsFailure(t *testing.T) {
+	rules, err := ParseRulesFromFile("../examples/rules_kafka.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages, err := ParseMessagesFromFile("../examples/messages_kafka.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages.Messages[0].ExpectedDecision = "BLOCK"
+	messages.Messages[1].ExpectedRuleID = "4"
+	messages.Messages[2].ExpectedDecision = ""
+	messages.Messages[2].ExpectedRuleID = ""
+
+	report := CheckExpectations(messages, rules)
+	if report.Passed != 3 || report.Failed != 2 || report.Skipped != 1 {
+		t.Fatalf("report:\n%v", report)
+	}
+	expected := []string{`decision: expected block, got allow`, `rule_id: expected "4", got "1"`}
+	for i, diff := range expected {
+		if report.Results[i].Pass || report.Results[i].Diff != diff {
+			t.Errorf("result %v: %+v (expected diff: %v)", i, report.Results[i], diff)
+		}
+	}
+
+	_, err = ParseMessages([]byte("messages:\n- message_id: 0\n  expected_decision: deny\n"))
+	if err == nil {
+		t.Errorf("expected_decision deny should be rejected")
+	}
+}
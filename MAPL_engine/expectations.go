@@ -0,0 +1,106 @@
+package MAPL_engine
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// decisionNames are the names of the decisions in expected_decision
+var decisionNames = map[int]string{
+	DEFAULT: "default",
+	ALLOW:   "allow",
+	ALERT:   "alert",
+	BLOCK:   "block",
+}
+
+// decisionByName returns the decision of a name (in upper case or in lower case)
+func decisionByName(name string) (int, bool) {
+	for decision, n := range decisionNames {
+		if strings.ToLower(name) == n {
+			return decision, true
+		}
+	}
+	return DEFAULT, false
+}
+
+// ExpectationResult is the result of the test of one message with its expected decision (and expected rule id)
+type ExpectationResult struct {
+	MessageIndex     int    `json:"MessageIndex"`
+	MessageID        string `json:"MessageID,omitempty"`
+	ExpectedDecision string `json:"ExpectedDecision"`
+	Decision         string `json:"Decision"`
+	ExpectedRuleID   string `json:"ExpectedRuleID,omitempty"`
+	RuleID           string `json:"RuleID,omitempty"` // the rule_id of the decision
+	Pass             bool   `json:"Pass"`
+	Diff             string `json:"Diff,omitempty"` // the difference between the expected and the actual results
+}
+
+// ExpectationReport is the result of CheckExpectations
+type ExpectationReport struct {
+	Results []ExpectationResult `json:"Results"`
+	Passed  int                 `json:"Passed"`
+	Failed  int                 `json:"Failed"`
+	Skipped int                 `json:"Skipped"` // messages without expected_decision and expected_rule_id
+}
+
+// CheckExpectations checks each message with the rules (with the Check function) and compares the decision with the message's expected_decision
+// and the rule_id of the decision with the message's expected_rule_id. Messages without expectations are skipped.
+func CheckExpectations(messages *Messages, rules *Rules) ExpectationReport {
+	report := ExpectationReport{Results: []ExpectationResult{}}
+	for i := range messages.Messages {
+		message := &messages.Messages[i]
+		if message.ExpectedDecision == "" && message.ExpectedRuleID == "" {
+			report.Skipped++
+			continue
+		}
+
+		decision, _, relevantRuleIndex, _, _ := Check(message, rules)
+		result := ExpectationResult{
+			MessageIndex:     i,
+			MessageID:        message.MessageID,
+			ExpectedDecision: strings.ToLower(message.ExpectedDecision),
+			Decision:         decisionNames[decision],
+			ExpectedRuleID:   message.ExpectedRuleID,
+			Pass:             true,
+		}
+		if relevantRuleIndex >= 0 {
+			result.RuleID = rules.Rules[relevantRuleIndex].RuleID
+		}
+
+		diffs := []string{}
+		if result.ExpectedDecision != "" && result.ExpectedDecision != result.Decision {
+			diffs = append(diffs, fmt.Sprintf("decision: expected %v, got %v", result.ExpectedDecision, result.Decision))
+		}
+		if result.ExpectedRuleID != "" && result.ExpectedRuleID != result.RuleID {
+			diffs = append(diffs, fmt.Sprintf("rule_id: expected %q, got %q", result.ExpectedRuleID, result.RuleID))
+		}
+		if len(diffs) > 0 {
+			result.Pass = false
+			result.Diff = strings.Join(diffs, "; ")
+			report.Failed++
+		} else {
+			report.Passed++
+		}
+		report.Results = append(report.Results, result)
+	}
+	return report
+}
+
+// String returns the report as text: one line for each message and a summary
+func (report ExpectationReport) String() string {
+	var buf bytes.Buffer
+	for _, result := range report.Results {
+		if result.Pass {
+			fmt.Fprintf(&buf, "PASS message #%v: %v", result.MessageIndex, result.Decision)
+			if result.RuleID != "" {
+				fmt.Fprintf(&buf, " by rule #%v", result.RuleID)
+			}
+			buf.WriteString("\n")
+		} else {
+			fmt.Fprintf(&buf, "FAIL message #%v: %v\n", result.MessageIndex, result.Diff)
+		}
+	}
+	fmt.Fprintf(&buf, "%v passed, %v failed, %v skipped\n", report.Passed, report.Failed, report.Skipped)
+	return buf.String()
+}
@@ -0,0 +1,245 @@
+package MAPL_engine
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var ErrInvalidLabelSelector = errors.New("invalid label selector")
+
+// LabelSelector is a Kubernetes-style label selector: a list of requirements that must all be satisfied by the labels.
+// example: "app=web,tier in (front,edge),!canary"
+type LabelSelector struct {
+	Requirements []LabelRequirement `json:"Requirements,omitempty" bson:"Requirements,omitempty"`
+}
+
+// LabelRequirement is one requirement of a label selector. The operator is one of "=", "!=", "in", "notin", "exists" and "!" (does not exist).
+type LabelRequirement struct {
+	Key      string   `json:"Key" bson:"Key"`
+	Operator string   `json:"Operator" bson:"Operator"`
+	Values   []string `json:"Values,omitempty" bson:"Values,omitempty"`
+}
+
+// ParseLabelSelector parses a label selector. The requirements are separated by commas:
+//
+//	key=value, key==value, key!=value, key in (value1,value2), key notin (value1,value2),
+//	key or key exists (the key exists), !key or key !exists (the key does not exist)
+//
+// The lists of values of in and notin must not be empty.
+func ParseLabelSelector(str string) (*LabelSelector, error) {
+	selector := &LabelSelector{}
+	parts, err := splitLabelSelector(str)
+	if err != nil {
+		return nil, err
+	}
+	for _, part := range parts {
+		requirement, err := parseLabelRequirement(part)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q: %v", ErrInvalidLabelSelector, str, err)
+		}
+		selector.Requirements = append(selector.Requirements, requirement)
+	}
+	if len(selector.Requirements) == 0 {
+		return nil, fmt.Errorf("%w: %q is empty", ErrInvalidLabelSelector, str)
+	}
+	return selector, nil
+}
+
+// splitLabelSelector splits the selector by the commas that are not in parentheses
+func splitLabelSelector(str string) ([]string, error) {
+	parts := []string{}
+	depth := 0
+	start := 0
+	for i, r := range str {
+		switch r {
+		case '(':
+			depth++
+			if depth > 1 {
+				return nil, fmt.Errorf("%w: %q has nested parentheses", ErrInvalidLabelSelector, str)
+			}
+		case ')':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("%w: %q has unbalanced parentheses", ErrInvalidLabelSelector, str)
+			}
+		case ',':
+			if depth == 0 {
+				parts = append(parts, str[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("%w: %q has unbalanced parentheses", ErrInvalidLabelSelector, str)
+	}
+	return append(parts, str[start:]), nil
+}
+
+func parseLabelRequirement(str string) (LabelRequirement, error) {
+	str = strings.TrimSpace(str)
+	if str == "" {
+		return LabelRequirement{}, fmt.Errorf("empty requirement")
+	}
+
+	if strings.HasPrefix(str, "!") && !strings.ContainsAny(str, "=()") {
+		return newLabelRequirement(str[1:], "!", nil)
+	}
+	if i := strings.Index(str, "!="); i >= 0 {
+		return newLabelRequirement(str[:i], "!=", []string{str[i+2:]})
+	}
+	if i := strings.Index(str, "=="); i >= 0 {
+		return newLabelRequirement(str[:i], "=", []string{str[i+2:]})
+	}
+	if i := strings.Index(str, "="); i >= 0 {
+		return newLabelRequirement(str[:i], "=", []string{str[i+1:]})
+	}
+
+	fields := strings.Fields(str)
+	if len(fields) == 1 {
+		return newLabelRequirement(str, "exists", nil)
+	}
+	if len(fields) == 2 && fields[1] == "exists" {
+		return newLabelRequirement(fields[0], "exists", nil)
+	}
+	if len(fields) == 2 && fields[1] == "!exists" {
+		return newLabelRequirement(fields[0], "!", nil)
+	}
+	if len(fields) < 2 || (fields[1] != "in" && fields[1] != "notin") {
+		return LabelRequirement{}, fmt.Errorf("%q is not a requirement (examples: app=web, tier in (front,edge))", str)
+	}
+	values := strings.TrimSpace(strings.TrimPrefix(str[len(fields[0]):], " "))
+	values = strings.TrimSpace(strings.TrimPrefix(values, fields[1]))
+	if !strings.HasPrefix(values, "(") || !strings.HasSuffix(values, ")") {
+		return LabelRequirement{}, fmt.Errorf("%q: the values of %v must be in parentheses", str, fields[1])
+	}
+	if strings.TrimSpace(values[1:len(values)-1]) == "" {
+		return LabelRequirement{}, fmt.Errorf("%q: the list of values of %v is empty", str, fields[1])
+	}
+	list := []string{}
+	for _, v := range strings.Split(values[1:len(values)-1], ",") {
+		list = append(list, strings.TrimSpace(v))
+	}
+	return newLabelRequirement(fields[0], fields[1], list)
+}
+
+func newLabelRequirement(key, operator string, values []string) (LabelRequirement, error) {
+	key = strings.TrimSpace(key)
+	if key == "" || strings.ContainsAny(key, " !=(),") {
+		return LabelRequirement{}, fmt.Errorf("%q is not a label key", key)
+	}
+	for i, v := range values {
+		values[i] = strings.TrimSpace(v)
+		if strings.ContainsAny(values[i], " !=(),") {
+			return LabelRequirement{}, fmt.Errorf("%q is not a label value", values[i])
+		}
+	}
+	return LabelRequirement{Key: key, Operator: operator, Values: values}, nil
+}
+
+// Matches tests that the labels satisfy all of the requirements of the selector
+func (s *LabelSelector) Matches(labels map[string]string) bool {
+	if s == nil {
+		return false
+	}
+	for _, r := range s.Requirements {
+		if !r.matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *LabelRequirement) matches(labels map[string]string) bool {
+	value, exists := labels[r.Key]
+	switch r.Operator {
+	case "exists":
+		return exists
+	case "!":
+		return !exists
+	case "=", "in":
+		return exists && isValueInList(value, r.Values)
+	case "!=", "notin": // as in kubernetes, true if the key does not exist
+		return !exists || !isValueInList(value, r.Values)
+	}
+	return false
+}
+
+func isValueInList(value string, list []string) bool {
+	for _, v := range list {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the selector in its canonical form
+func (s *LabelSelector) String() string {
+	if s == nil {
+		return ""
+	}
+	parts := make([]string, len(s.Requirements))
+	for i, r := range s.Requirements {
+		switch r.Operator {
+		case "exists":
+			parts[i] = r.Key
+		case "!":
+			parts[i] = "!" + r.Key
+		case "in", "notin":
+			parts[i] = r.Key + " " + r.Operator + " (" + strings.Join(r.Values, ",") + ")"
+		default:
+			parts[i] = r.Key + r.Operator + strings.Join(r.Values, "")
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// selectorMethods are the methods of the senderLabels and receiverLabels conditions. The value of SELECTOR and NSELECTOR is a label selector.
+var selectorMethods = []string{"SELECTOR", "NSELECTOR", "EX", "NEX"}
+
+// isLabelSelectorAttribute returns true for the attributes that are tested with a label selector (senderLabels, receiverLabels)
+func isLabelSelectorAttribute(attribute string) bool {
+	return attribute == "senderLabels" || attribute == "receiverLabels"
+}
+
+// convertSelectorCondition parses the label selector of a SELECTOR or NSELECTOR condition (once, when the rules are read)
+func convertSelectorCondition(c *Condition) error {
+	if isExistenceMethod(c.Method) {
+		return nil
+	}
+	selector, err := ParseLabelSelector(c.Value)
+	if err != nil {
+		return err
+	}
+	c.ValueSelector = selector
+	return nil
+}
+
+// testSelectorCondition tests the labels of the message with the label selector of the condition. It returns the result and the labels (as a string).
+func testSelectorCondition(c *Condition, message *MessageAttributes) (bool, string) {
+	labels := message.SourceLabels
+	if c.Attribute == "receiverLabels" {
+		labels = message.DestinationLabels
+	}
+	result := c.ValueSelector.Matches(labels)
+	if c.Method == "NSELECTOR" || c.Method == "nselector" {
+		result = !result
+	}
+	return result, formatLabels(labels)
+}
+
+// formatLabels returns the labels as a string sorted by key (used in the trace). example: "app=web,tier=front"
+func formatLabels(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k + "=" + labels[k]
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,97 @@
+package MAPL_engine
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseLabelSelector(t *testing.T) {
+	tests := []struct {
+		selector     string
+		requirements []LabelRequirement
+	}{
+		{"app=web", []LabelRequirement{{Key: "app", Operator: "=", Values: []string{"web"}}}},
+		{"app==web, tier!=back", []LabelRequirement{{Key: "app", Operator: "=", Values: []string{"web"}}, {Key: "tier", Operator: "!=", Values: []string{"back"}}}},
+		{"tier in (front, edge)", []LabelRequirement{{Key: "tier", Operator: "in", Values: []string{"front", "edge"}}}},
+		{"tier notin (back)", []LabelRequirement{{Key: "tier", Operator: "notin", Values: []string{"back"}}}},
+		{"app,team exists", []LabelRequirement{{Key: "app", Operator: "exists"}, {Key: "team", Operator: "exists"}}},
+		{"!canary,beta !exists", []LabelRequirement{{Key: "canary", Operator: "!"}, {Key: "beta", Operator: "!"}}},
+	}
+	for _, test := range tests {
+		selector, err := ParseLabelSelector(test.selector)
+		if err != nil {
+			t.Errorf("%q: %v", test.selector, err)
+			continue
+		}
+		if !reflect.DeepEqual(selector.Requirements, test.requirements) {
+			t.Errorf("%q: unexpected requirements %+v", test.selector, selector.Requirements)
+		}
+	}
+
+	for _, selector := range []string{
+		"",
+		" , ",
+		"app=web,",
+		"tier in ()",
+		"tier notin ( )",
+		"tier in front",
+		"tier in (front",
+		"tier in ((front))",
+		"tier) in (front",
+		"tier like front",
+		"=web",
+		"app=w b",
+		"app=(web)",
+		"!",
+	} {
+		if _, err := ParseLabelSelector(selector); !errors.Is(err, ErrInvalidLabelSelector) {
+			t.Errorf("%q: expected an invalid label selector error, got %v", selector, err)
+		}
+	}
+}
+
+func TestLabelSelectorMatches(t *testing.T) {
+	labels := map[string]string{"app": "web", "tier": "front"}
+	tests := []struct {
+		selector string
+		labels   map[string]string
+		matches  bool
+	}{
+		{"app=web", labels, true},
+		{"app=api", labels, false},
+		{"app=web,tier=back", labels, false},
+		{"tier in (edge,front)", labels, true},
+		{"tier in (edge,back)", labels, false},
+		{"team in (shop)", labels, false}, // a missing key is not in the list
+		{"tier notin (back)", labels, true},
+		{"tier notin (front,back)", labels, false},
+		{"team notin (shop)", labels, true}, // a missing key is not in the list
+		{"app", labels, true},
+		{"team exists", labels, false},
+		{"!team", labels, true},
+		{"!app", labels, false},
+		{"team !exists", labels, true},
+		{"tier !exists", labels, false},
+		{"tier!=back", labels, true},
+		{"tier!=front", labels, false},
+		{"team!=shop", labels, true}, // as in kubernetes, true if the key does not exist
+		{"team!=shop", map[string]string{}, true},
+		{"!team", nil, true},
+		{"app=web", nil, false},
+	}
+	for _, test := range tests {
+		selector, err := ParseLabelSelector(test.selector)
+		if err != nil {
+			t.Fatalf("%q: %v", test.selector, err)
+		}
+		if matches := selector.Matches(test.labels); matches != test.matches {
+			t.Errorf("%q with %v: expected %v, got %v", test.selector, test.labels, test.matches, matches)
+		}
+	}
+
+	var selector *LabelSelector
+	if selector.Matches(labels) {
+		t.Errorf("a nil selector should not match")
+	}
+}
@@ -10,6 +10,8 @@ import (
 	"fmt"
 	"encoding/json"
 	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
 )
 
 // YamlReadMessageAttributes function reads message attributes from a yaml string
@@ -22,10 +24,14 @@ func YamlReadMessageAttributes(yamlString string) MessageAttributes {
 	}
 	//fmt.Printf("---values found:\n%+v\n\n", rule)
 
-	flag, outputString := IsNumberOfFieldsEqual(messageAttributes, yamlString)
-
-	if flag == false {
-		panic("number of fields in rules does not match number of fields in yaml file:\n" + outputString)
+	var root yamlv3.Node
+	err = yamlv3.Unmarshal([]byte(yamlString), &root)
+	if err != nil {
+		panic(&MessageError{MessageIndex: -1, Line: yamlErrorLine(err.Error()), Err: fmt.Errorf("%w: %v", ErrInvalidYaml, err)})
+	}
+	path, line, column, err := yamlUnknownField(&root, &messageAttributes)
+	if err != nil {
+		panic(&MessageError{MessageIndex: -1, Path: path, Line: line, Column: column, Err: err})
 	}
 
 	AddResourceType(&messageAttributes)
@@ -44,6 +50,10 @@ func YamlReadMessagesFromString(yamlString string) Messages {
 	}
 	//fmt.Printf("---values found:\n%+v\n\n", rule)
 
+	if messageErr := unknownMessageField([]byte(yamlString), &messages); messageErr != nil {
+		panic(messageErr)
+	}
+
 	addResourceTypeToMessages(&messages)
 	addTimeInfoToMessages(&messages)
 	addNetIpToMessages(&messages)
@@ -51,11 +61,6 @@ func YamlReadMessagesFromString(yamlString string) Messages {
 
 	// fmt.Println(messages)
 
-	flag, outputString := IsNumberOfFieldsEqual(messages, yamlString)
-	if flag == false {
-		panic("number of fields in rules does not match number of fields in yaml file:\n" + outputString)
-	}
-
 	return messages
 }
 
@@ -69,18 +74,141 @@ func YamlReadMessagesFromFile(filename string) Messages {
 	return messages
 }
 
+// ParseMessages function reads messages from yaml data. Unlike YamlReadMessagesFromString it does not panic on bad input.
+// The returned error is a *MessageError with the message index, message_id, path of the field and its line and column in the yaml data.
+func ParseMessages(data []byte) (*Messages, error) {
+
+	var root yamlv3.Node
+	err := yamlv3.Unmarshal(data, &root)
+	if err != nil {
+		line := yamlErrorLine(err.Error())
+		return nil, &MessageError{MessageIndex: yamlItemIndexAtLine(&root, "messages", line), Line: line, Err: fmt.Errorf("%w: %v", ErrInvalidYaml, err)}
+	}
+
+	var messages Messages
+	if len(root.Content) > 0 {
+		err = root.Decode(&messages)
+		if err != nil {
+			errStr := yamlDecodeErrors(err)[0]
+			line := yamlErrorLine(errStr)
+			messageIndex := yamlItemIndexAtLine(&root, "messages", line)
+			path := "messages"
+			if messageIndex >= 0 {
+				path = fmt.Sprintf("messages[%v]", messageIndex)
+			}
+			return nil, &MessageError{MessageIndex: messageIndex, Path: path, Line: line, Column: yamlColumnAtLine(&root, line), Err: fmt.Errorf("%w: %v", ErrInvalidYaml, errStr)}
+		}
+	}
+
+	path, line, column, err := yamlUnknownField(&root, &messages)
+	if err != nil {
+		return nil, newUnknownMessageFieldError(&root, &messages, path, line, column, err)
+	}
+
+	for i := range messages.Messages {
+		err = convertMessage(&messages.Messages[i])
+		if err != nil {
+			return nil, newMessageError(&root, i, messages.Messages[i].MessageID, err)
+		}
+	}
+
+	return &messages, nil
+}
+
+// ParseMessagesFromFile function reads messages from a yaml file. See ParseMessages.
+func ParseMessagesFromFile(filename string) (*Messages, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return ParseMessages(data)
+}
+
+// convertMessage adds the attributes extracted from the message's string fields (resource type, time info, net.IP and labels)
+func convertMessage(message *MessageAttributes) error {
+	if message.ExpectedDecision != "" {
+		if _, ok := decisionByName(message.ExpectedDecision); !ok {
+			return &fieldError{"expected_decision", fmt.Errorf("%w: %q (allow, alert, block or default)", ErrInvalidDecision, message.ExpectedDecision)}
+		}
+	}
+	AddResourceType(message)
+	err := addTimeInfoToMessage(message)
+	if err != nil {
+		return err
+	}
+	AddNetIpToMessage(message)
+	return parseLabelsJson(message)
+}
+
+// unknownMessageField returns a *MessageError if a key in the yaml data is not a field of the messages (nil if all of the keys are known).
+// It is used by the functions that decode the messages with yaml.v2.
+func unknownMessageField(data []byte, messages *Messages) *MessageError {
+	var root yamlv3.Node
+	err := yamlv3.Unmarshal(data, &root)
+	if err != nil {
+		return &MessageError{MessageIndex: -1, Line: yamlErrorLine(err.Error()), Err: fmt.Errorf("%w: %v", ErrInvalidYaml, err)}
+	}
+	path, line, column, err := yamlUnknownField(&root, messages)
+	if err != nil {
+		return newUnknownMessageFieldError(&root, messages, path, line, column, err)
+	}
+	return nil
+}
+
+// newUnknownMessageFieldError returns the error of an unknown key with the message it is in
+func newUnknownMessageFieldError(root *yamlv3.Node, messages *Messages, path string, line, column int, err error) *MessageError {
+	messageIndex := yamlItemIndexAtLine(root, "messages", line)
+	messageID := ""
+	if messageIndex >= 0 && messageIndex < len(messages.Messages) {
+		messageID = messages.Messages[messageIndex].MessageID
+	}
+	return &MessageError{MessageIndex: messageIndex, MessageID: messageID, Path: path, Line: line, Column: column, Err: err}
+}
+
+// newMessageError adds the message details and the position in the yaml input to the error of one of the message's fields
+func newMessageError(root *yamlv3.Node, messageIndex int, messageID string, err error) *MessageError {
+	path := fmt.Sprintf("messages[%v]", messageIndex)
+	if fErr, ok := err.(*fieldError); ok {
+		path += "." + fErr.path
+		err = fErr.err
+	}
+	line, column := yamlPosition(root, path)
+	return &MessageError{MessageIndex: messageIndex, MessageID: messageID, Path: path, Line: line, Column: column, Err: err}
+}
+
 // AddResourceType function adds resource type to one message by the resource protocol for HTTP and TCP. For KAFKA the resource_type need to be filled in the message attributes.
 func AddResourceType(message *MessageAttributes){
 	// add resource_type by the resource_protocol
 	// we have resource_type to allow for several types per one protocol.
 	//
-	message.ContextType=""
-	switch message.ContextProtocol{ // these are the only protocols we currently support
-	case "HTTP","http":
+	switch strings.ToLower(message.ContextProtocol){ // these are the only protocols we currently support (in any case)
+	case "http":
 		message.ContextType = "httpPath"
-	case "TCP","tcp":
+	case "tcp":
 		message.ContextType = "port"
+	case "grpc": // the resources are the service and the method of the path /package.Service/Method
+		message.ContextType = "grpcMethod"
+		message.GrpcService, message.GrpcMethod = parseGrpcPath(message.RequestPath)
+	case "kafka": // the resource type is given in the message (request_type) since there are several types
+		if message.ContextType == "" {
+			message.ContextType = "kafkaTopic"
+			if message.KafkaTopic == "" && message.KafkaConsumerGroup != "" {
+				message.ContextType = "consumerGroup"
+			}
+		}
+	default:
+		message.ContextType=""
+	}
+}
+
+// parseGrpcPath splits the path of a gRPC request ("/package.Service/Method") to the service ("package.Service") and the method ("Method")
+func parseGrpcPath(path string) (service string, method string) {
+	path = strings.TrimPrefix(path, "/")
+	i := strings.LastIndex(path, "/")
+	if i < 0 {
+		return path, ""
 	}
+	return path[:i], path[i+1:]
 }
 
 // addResourceTypeToMessages function adds resource type to all messages
@@ -95,17 +223,33 @@ func addResourceTypeToMessages(messages *Messages) {
 
 
 // AddTimeInfoToMessage function parses timestamp data in one message and extract the second, minutes and hours since midnight.
+// If the message has no timestamp then the time attributes do not exist in the message (see the EX and NEX methods).
 func AddTimeInfoToMessage(message *MessageAttributes) {
 	//
 	// extract timestamp info
 	//
 
+	err := addTimeInfoToMessage(message)
+	if err != nil {
+		panic(ErrInvalidTime.Error())
+	}
+}
+
+// addTimeInfoToMessage is the error-returning version of AddTimeInfoToMessage
+func addTimeInfoToMessage(message *MessageAttributes) error {
+
+	if message.RequestTime == "" { // no time info
+		return nil
+	}
+
 	//t, err := time.Parse(time.RFC3339,"2018-07-29T14:30:00-07:00")
 	t, err := time.Parse(time.RFC3339,message.RequestTime)
 	if err!=nil{
-		panic("error in parsing message time")
+		return &fieldError{"request_time", fmt.Errorf("%w: %q", ErrInvalidTime, message.RequestTime)}
 	}
 
+	message.RequestTimeParsed = t
+
 	nanosecondsFromMidnight := float64(((t.Hour()*60+t.Minute())*60+t.Second())*1e9+t.Nanosecond())
 
 	message.RequestTimeSecondsFromMidnightUTC = nanosecondsFromMidnight/1e9
@@ -114,6 +258,7 @@ func AddTimeInfoToMessage(message *MessageAttributes) {
 
 	message.RequestTimeMinutesParity = (int64(message.RequestTimeMinutesFromMidnightUTC)%60)%2
 
+	return nil
 }
 
 // addTimeInfoToMessages function parses timestamp data for all messages
@@ -159,6 +304,28 @@ func parseLabelsJsonOfMessage(message *MessageAttributes) {
 
 }
 
+// parseLabelsJson is the error-returning version of parseLabelsJsonOfMessage. Empty labels strings are not an error.
+func parseLabelsJson(message *MessageAttributes) error {
+
+	if message.SourceLabelsJson != "" {
+		str := addQuotesToJsonString(message.SourceLabelsJson)
+		err := json.Unmarshal([]byte(str), &message.SourceLabels)
+		if err != nil {
+			return &fieldError{"sender_labels", fmt.Errorf("%w: %q", ErrInvalidLabelsJson, message.SourceLabelsJson)}
+		}
+	}
+
+	if message.DestinationLabelsJson != "" {
+		str := addQuotesToJsonString(message.DestinationLabelsJson)
+		err := json.Unmarshal([]byte(str), &message.DestinationLabels)
+		if err != nil {
+			return &fieldError{"receiver_labels", fmt.Errorf("%w: %q", ErrInvalidLabelsJson, message.DestinationLabelsJson)}
+		}
+	}
+
+	return nil
+}
+
 func parseLabelsJsonOfMessages(messages *Messages) {
 	for i, _ := range (messages.Messages) {
 		parseLabelsJsonOfMessage(&messages.Messages[i])
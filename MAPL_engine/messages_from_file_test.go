@@ -0,0 +1,61 @@
+package MAPL_engine
+
+import (
+	"testing"
+)
+
+func TestAddResourceType(t *testing.T) {
+	tests := []struct {
+		protocol, requestType, path string
+		contextType                 string
+	}{
+		{"http", "", "/books", "httpPath"},
+		{"Http", "", "/books", "httpPath"},
+		{"TCP", "", "", "port"},
+		{"Tcp", "", "", "port"},
+		{"grpc", "", "/payments.PaymentService/Authorize", "grpcMethod"},
+		{"Grpc", "", "/payments.PaymentService/Authorize", "grpcMethod"},
+		{"Kafka", "", "", "kafkaTopic"},
+		{"kafka", "consumerGroup", "", "consumerGroup"},
+		{"udp", "port", "", ""},
+	}
+	for _, test := range tests {
+		message := &MessageAttributes{ContextProtocol: test.protocol, ContextType: test.requestType, RequestPath: test.path}
+		AddResourceType(message)
+		if message.ContextType != test.contextType {
+			t.Errorf("%v: expected resource type %q, got %q", test.protocol, test.contextType, message.ContextType)
+		}
+		if test.contextType == "grpcMethod" && (message.GrpcService != "payments.PaymentService" || message.GrpcMethod != "Authorize") {
+			t.Errorf("%v: unexpected gRPC service %q and method %q", test.protocol, message.GrpcService, message.GrpcMethod)
+		}
+	}
+}
+
+// TestProtocolCase tests that the protocol of the messages is not case sensitive
+func TestProtocolCase(t *testing.T) {
+	rules, err := ParseRulesFromFile("../examples/rules_grpc.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages, err := ParseMessages([]byte(`messages:
+  - message_id: 0
+    sender_service: orders.my_namespace
+    receiver_service: payments.my_namespace
+    request_protocol: Grpc
+    request_path: /payments.PaymentService/Authorize
+    request_method: POST
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := &messages.Messages[0]
+	if message.ContextType != "grpcMethod" {
+		t.Errorf("unexpected resource type %q", message.ContextType)
+	}
+	if decision, _, _, _, _ := Check(message, rules); decision != ALLOW {
+		t.Errorf("expected allow, got %v", decision)
+	}
+	if decision, _, _, _, _ := NewPolicy(rules).Check(message); decision != ALLOW {
+		t.Errorf("expected allow from the policy, got %v", decision)
+	}
+}
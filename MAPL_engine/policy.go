@@ -0,0 +1,228 @@
+package MAPL_engine
+
+import (
+	"math/bits"
+	"net"
+	"strings"
+)
+
+// Policy is a compiled set of rules. The rules are indexed (bucketed) by protocol and resource type, by exact sender and
+// receiver names and by CIDR prefixes so that Check evaluates only the rules that may apply to the message.
+// The rules must not be changed after the policy is compiled.
+type Policy struct {
+	Rules *Rules
+
+	protocolIndex ruleIndex // keyed by protocol and resource type
+	senderIndex   ruleIndex // keyed by sender service name, sender ip and sender CIDR
+	receiverIndex ruleIndex // keyed by receiver service name, receiver ip and receiver CIDR
+}
+
+// ruleIndex maps keys to lists of rule indices (sorted in ascending order).
+// rules that cannot be indexed by a key (wildcards etc.) are kept in the "any" list and are candidates for all messages.
+type ruleIndex struct {
+	byName map[string][]int
+	byIP   map[string][]int
+	byCIDR map[cidrMask]map[string][]int // CIDR prefix -> network address -> rules
+	masks  []cidrMask
+	any    []int
+}
+
+type cidrMask struct {
+	ones, bits int
+}
+
+// NewPolicy compiles the rules into a policy. The rules are expected to be converted already (as returned by
+// YamlReadRulesFromFile, ParseRules etc.).
+func NewPolicy(rules *Rules) *Policy {
+	p := &Policy{
+		Rules:         rules,
+		protocolIndex: newRuleIndex(),
+		senderIndex:   newRuleIndex(),
+		receiverIndex: newRuleIndex(),
+	}
+	for i := range rules.Rules {
+		rule := &rules.Rules[i]
+		p.protocolIndex.addProtocol(i, rule)
+		p.senderIndex.addSendersReceivers(i, rule.Sender.SenderList)
+		p.receiverIndex.addSendersReceivers(i, rule.Receiver.ReceiverList)
+	}
+	return p
+}
+
+// Check is the same as the Check function (with the same outputs) but evaluates only the candidate rules of the message.
+func (p *Policy) Check(message *MessageAttributes) (decision int, descisionString string, relevantRuleIndex int, results []int, appliedRulesIndices []int) {
+
+	N := len(p.Rules.Rules)
+	results = make([]int, N)
+
+	candidates := p.protocolIndex.candidates(N, protocolKeys(message), "", nil)
+	candidates.and(p.senderIndex.candidates(N, []string{message.SourceService}, message.SourceIp, message.SourceNetIp))
+	candidates.and(p.receiverIndex.candidates(N, []string{message.DestinationService}, message.DestinationIp, message.DestinationNetIp))
+
+	candidates.forEach(func(i int) {
+		results[i] = CheckOneRule(message, &p.Rules.Rules[i])
+	})
+
+	decision, descisionString, relevantRuleIndex, appliedRulesIndices = combineResults(p.Rules, results)
+	return decision, descisionString, relevantRuleIndex, results, appliedRulesIndices
+}
+
+func newRuleIndex() ruleIndex {
+	return ruleIndex{
+		byName: make(map[string][]int),
+		byIP:   make(map[string][]int),
+		byCIDR: make(map[cidrMask]map[string][]int),
+	}
+}
+
+// protocolKey is the key of the protocol index. The protocol is compared regardless of case and the resource type is compared exactly.
+func protocolKey(protocol, resourceType string) string {
+	return strings.ToLower(protocol) + "|" + resourceType
+}
+
+// protocolKeys returns the keys of the protocol index that match the message (one key for each of the message's resource types)
+func protocolKeys(message *MessageAttributes) []string {
+	resourceTypes := messageResourceTypes(message)
+	keys := make([]string, len(resourceTypes))
+	for i, resourceType := range resourceTypes {
+		keys[i] = protocolKey(message.ContextProtocol, resourceType)
+	}
+	return keys
+}
+
+// addProtocol adds the rule by each of the protocols and resource types in the rule's lists. If one of them has wildcards
+// then the rule is added to the "any" list.
+func (index *ruleIndex) addProtocol(i int, rule *Rule) {
+	protocols := splitPatternList(rule.Protocol)
+	resourceTypes := splitPatternList(rule.Resource.ResourceType)
+	if protocols == nil || resourceTypes == nil {
+		index.any = append(index.any, i)
+		return
+	}
+	for _, protocol := range protocols {
+		for _, resourceType := range resourceTypes {
+			key := protocolKey(protocol, resourceType)
+			l := index.byName[key]
+			if len(l) > 0 && l[len(l)-1] == i { // the same key twice in the lists
+				continue
+			}
+			index.byName[key] = append(l, i)
+		}
+	}
+}
+
+// splitPatternList splits a list separated by ';' (spaces are removed as when converting to regex).
+// It returns nil if one of the patterns has wildcards (or regex special characters) or if the list is empty.
+func splitPatternList(str string) []string {
+	list := strings.Split(strings.Replace(str, " ", "", -1), ";")
+	for _, pattern := range list {
+		if pattern == "" || strings.ContainsAny(pattern, "*?\\+()|[]{}") {
+			return nil
+		}
+	}
+	return list
+}
+
+// addSendersReceivers adds the rule by the expanded sender (or receiver) list. A message may match any of the list's entries
+// so the rule is added under all of them. If one of the entries cannot be indexed then the rule is added to the "any" list.
+func (index *ruleIndex) addSendersReceivers(i int, list []ExpandedSenderReceiver) {
+	type entry struct {
+		m   map[string][]int
+		key string
+	}
+	entries := []entry{}
+	for _, e := range list {
+		switch e.Type {
+		case "subnet":
+			if e.IsIP {
+				entries = append(entries, entry{index.byIP, e.Name})
+			} else if e.IsCIDR {
+				ones, size := e.CIDR.Mask.Size()
+				mask := cidrMask{ones, size}
+				if _, ok := index.byCIDR[mask]; !ok {
+					index.byCIDR[mask] = make(map[string][]int)
+					index.masks = append(index.masks, mask)
+				}
+				entries = append(entries, entry{index.byCIDR[mask], e.CIDR.IP.String()})
+			} else {
+				index.any = append(index.any, i)
+				return
+			}
+		case "*", "service":
+			name := strings.Replace(e.Name, " ", "", -1)   // spaces are removed when converting to regex
+			if strings.ContainsAny(name, "*?\\+()|[]{}") { // wildcards (or regex special characters)
+				index.any = append(index.any, i)
+				return
+			}
+			entries = append(entries, entry{index.byName, name})
+		default:
+			index.any = append(index.any, i)
+			return
+		}
+	}
+	for _, en := range entries {
+		l := en.m[en.key]
+		if len(l) > 0 && l[len(l)-1] == i { // the same key twice in the list
+			continue
+		}
+		en.m[en.key] = append(l, i)
+	}
+}
+
+// candidates returns the set of rules that may match the message's name (or names), ip string and net.IP
+func (index *ruleIndex) candidates(N int, names []string, ip string, netIp net.IP) bitset {
+	b := newBitset(N)
+	b.setAll(index.any)
+	for _, name := range names {
+		b.setAll(index.byName[name])
+	}
+	if ip != "" {
+		b.setAll(index.byIP[ip])
+	}
+	if netIp != nil {
+		for _, mask := range index.masks {
+			ipToMask := netIp
+			if mask.bits == 8*net.IPv4len {
+				ipToMask = netIp.To4()
+			} else if netIp.To4() != nil { // IPv4 addresses are not contained in IPv6 networks (as in net.IPNet.Contains)
+				ipToMask = nil
+			}
+			if ipToMask == nil {
+				continue
+			}
+			network := ipToMask.Mask(net.CIDRMask(mask.ones, mask.bits))
+			b.setAll(index.byCIDR[mask][network.String()])
+		}
+	}
+	return b
+}
+
+// bitset is a set of rule indices
+type bitset []uint64
+
+func newBitset(N int) bitset {
+	return make(bitset, (N+63)/64)
+}
+
+func (b bitset) setAll(indices []int) {
+	for _, i := range indices {
+		b[i/64] |= 1 << uint(i%64)
+	}
+}
+
+func (b bitset) and(other bitset) {
+	for i := range b {
+		b[i] &= other[i]
+	}
+}
+
+// forEach calls f with the indices in the set in ascending order
+func (b bitset) forEach(f func(i int)) {
+	for w, word := range b {
+		for word != 0 {
+			bit := bits.TrailingZeros64(word)
+			f(w*64 + bit)
+			word &^= 1 << uint(bit)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package MAPL_engine
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// generateRules creates N rules with a mix of exact names, wildcards, subnets, protocols and conditions
+func generateRules(N int) *Rules {
+	r := rand.New(rand.NewSource(1))
+	protocols := []string{"http", "tcp", "HTTP", "*"}
+	resourceTypes := map[string]string{"http": "httpPath", "HTTP": "httpPath", "tcp": "port", "*": "*"}
+	decisions := []string{"allow", "alert", "block"}
+
+	rules := &Rules{}
+	for i := 0; i < N; i++ {
+		rule := Rule{RuleID: fmt.Sprint(i)}
+
+		switch r.Intn(10) {
+		case 0:
+			rule.Sender = Sender{SenderName: fmt.Sprintf("srv%v.*", r.Intn(N)), SenderType: "service"}
+		case 1:
+			rule.Sender = Sender{SenderName: fmt.Sprintf("10.%v.0.0/16", r.Intn(4)), SenderType: "subnet"}
+		case 2:
+			rule.Sender = Sender{SenderName: fmt.Sprintf("10.0.0.%v", r.Intn(4)), SenderType: "subnet"}
+		case 3:
+			rule.Sender = Sender{SenderName: "*", SenderType: "*"}
+		default:
+			rule.Sender = Sender{SenderName: fmt.Sprintf("srv%v.ns", r.Intn(N)), SenderType: "service"}
+		}
+		if r.Intn(5) == 0 {
+			rule.Receiver = Receiver{ReceiverName: fmt.Sprintf("srv%v.ns;srv%v.ns", r.Intn(N), r.Intn(N)), ReceiverType: "service"}
+		} else {
+			rule.Receiver = Receiver{ReceiverName: fmt.Sprintf("srv%v.ns", r.Intn(N)), ReceiverType: "service"}
+		}
+
+		rule.Protocol = protocols[r.Intn(len(protocols))]
+		rule.Resource = Resource{ResourceType: resourceTypes[rule.Protocol], ResourceName: "/*"}
+		rule.Operation = "*"
+		rule.Decision = decisions[r.Intn(len(decisions))]
+		if r.Intn(4) == 0 {
+			rule.DNFConditions = []ANDConditions{{ANDConditions: []Condition{{Attribute: "payloadSize", Method: "LE", Value: "1024"}}}}
+		}
+
+		ConvertFieldsToRegex(&rule)
+		ConvertConditionStringToIntFloatRegex(&rule)
+		rules.Rules = append(rules.Rules, rule)
+	}
+	return rules
+}
+
+// generateMessages creates M messages between the services of the generated rules
+func generateMessages(M, N int) []MessageAttributes {
+	r := rand.New(rand.NewSource(2))
+	protocols := []string{"http", "HTTP", "tcp", "kafka"}
+
+	messages := make([]MessageAttributes, M)
+	for i := range messages {
+		message := MessageAttributes{
+			SourceService:      fmt.Sprintf("srv%v.ns", r.Intn(N)),
+			SourceIp:           fmt.Sprintf("10.%v.0.%v", r.Intn(5), r.Intn(5)),
+			DestinationService: fmt.Sprintf("srv%v.ns", r.Intn(N)),
+			DestinationIp:      "192.168.1.1",
+			ContextProtocol:    protocols[r.Intn(len(protocols))],
+			RequestPath:        "/books",
+			RequestMethod:      "GET",
+			RequestSize:        int64(r.Intn(2048)),
+		}
+		AddResourceType(&message)
+		AddNetIpToMessage(&message)
+		messages[i] = message
+	}
+	return messages
+}
+
+// TestPolicyCheck tests that Policy.Check and Check give exactly the same outputs
+func TestPolicyCheck(t *testing.T) {
+	N := 1000
+	rules := generateRules(N)
+	policy := NewPolicy(rules)
+
+	messages := generateMessages(3000, N)
+	// messages that match the rules' senders and receivers
+	for i := 0; i < N; i++ {
+		rule := rules.Rules[i]
+		message := messages[i]
+		message.SourceService = rule.Sender.SenderList[0].Name
+		message.DestinationService = rule.Receiver.ReceiverList[0].Name
+		messages = append(messages, message)
+	}
+
+	applied := 0
+	for i := range messages {
+		decision1, str1, relevant1, results1, applied1 := Check(&messages[i], rules)
+		decision2, str2, relevant2, results2, applied2 := policy.Check(&messages[i])
+		if decision1 != decision2 || str1 != str2 || relevant1 != relevant2 || !reflect.DeepEqual(results1, results2) || !reflect.DeepEqual(applied1, applied2) {
+			t.Fatalf("message #%v: Check=(%v,%v,%v) Policy.Check=(%v,%v,%v)", i, decision1, relevant1, applied1, decision2, relevant2, applied2)
+		}
+		applied += len(applied1)
+	}
+	if applied == 0 {
+		t.Fatal("no rule was applied to any message")
+	}
+}
+
+// checkWithGoroutines is the previous implementation of Check (one goroutine per rule). It is used as a reference in the benchmarks.
+func checkWithGoroutines(message *MessageAttributes, rules *Rules) (int, string, int, []int, []int) {
+	N := len(rules.Rules)
+	results := make([]int, N)
+	sem := make(chan int, N)
+	for i, rule := range rules.Rules {
+		go func(in_i int, in_rule Rule) {
+			results[in_i] = CheckOneRule(message, &in_rule)
+			sem <- 1
+		}(i, rule)
+	}
+	for i := 0; i < N; i++ {
+		<-sem
+	}
+	decision, decisionString, relevantRuleIndex, appliedRulesIndices := combineResults(rules, results)
+	return decision, decisionString, relevantRuleIndex, results, appliedRulesIndices
+}
+
+func benchmarkCheck(b *testing.B, check func(message *MessageAttributes, rules *Rules, policy *Policy)) {
+	N := 10000
+	rules := generateRules(N)
+	policy := NewPolicy(rules)
+	messages := generateMessages(1000, N)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		check(&messages[i%len(messages)], rules, policy)
+	}
+}
+
+func BenchmarkCheckWithGoroutines10kRules(b *testing.B) {
+	benchmarkCheck(b, func(message *MessageAttributes, rules *Rules, policy *Policy) { checkWithGoroutines(message, rules) })
+}
+
+func BenchmarkCheck10kRules(b *testing.B) {
+	benchmarkCheck(b, func(message *MessageAttributes, rules *Rules, policy *Policy) { Check(message, rules) })
+}
+
+func BenchmarkPolicyCheck10kRules(b *testing.B) {
+	benchmarkCheck(b, func(message *MessageAttributes, rules *Rules, policy *Policy) { policy.Check(message) })
+}
+
+func BenchmarkNewPolicy10kRules(b *testing.B) {
+	rules := generateRules(10000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		NewPolicy(rules)
+	}
+}
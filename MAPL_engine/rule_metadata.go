@@ -0,0 +1,114 @@
+package MAPL_engine
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// the metadata of a rule (description, owner and tags) does not change the decisions. The validity period (validFrom, validUntil) does:
+// a rule applies only to messages with a request time in the period (and not to messages without a request time).
+// The values are timestamps (RFC3339) or dates with an optional time zone (as the values of the requestTimestamp conditions).
+// A date of validUntil is included in the period. example:
+//
+//	  - rule_id: temporary-access
+//	    description: read access for the migration of the books database
+//	    owner: books-team
+//	    tags: [migration, temporary]
+//	    validFrom: 2026-11-01
+//	    validUntil: 2026-11-30 Europe/Berlin
+//	    sender:
+//	      ...
+
+// convertValidity parses the validity period of the rule
+func convertValidity(rule *Rule) error {
+	rule.ValidFromTime, rule.ValidUntilTime = time.Time{}, time.Time{}
+	var err error
+	if rule.ValidFrom != "" {
+		rule.ValidFromTime, _, err = parseTimeValue(rule.ValidFrom)
+		if err != nil {
+			return &fieldError{"validFrom", fmt.Errorf("%w: %v", ErrInvalidValidity, err)}
+		}
+	}
+	if rule.ValidUntil != "" {
+		_, rule.ValidUntilTime, err = parseTimeValue(rule.ValidUntil)
+		if err != nil {
+			return &fieldError{"validUntil", fmt.Errorf("%w: %v", ErrInvalidValidity, err)}
+		}
+	}
+	if rule.ValidFrom != "" && rule.ValidUntil != "" && !rule.ValidFromTime.Before(rule.ValidUntilTime) {
+		return &fieldError{"validUntil", fmt.Errorf("%w: validUntil %q is before validFrom %q", ErrInvalidValidity, rule.ValidUntil, rule.ValidFrom)}
+	}
+	return nil
+}
+
+// hasValidity returns true if the rule has a validity period
+func hasValidity(rule *Rule) bool {
+	return !rule.ValidFromTime.IsZero() || !rule.ValidUntilTime.IsZero()
+}
+
+// IsValidAt returns true if the time is in the validity period of the rule (rules without a validity period are always valid)
+func IsValidAt(rule *Rule, t time.Time) bool {
+	if !rule.ValidFromTime.IsZero() && t.Before(rule.ValidFromTime) {
+		return false
+	}
+	if !rule.ValidUntilTime.IsZero() && !t.Before(rule.ValidUntilTime) {
+		return false
+	}
+	return true
+}
+
+// messageTime returns the request time of the message that is compared with the validity period of the rules
+// (false for messages without a request time, so the decisions do not depend on the time of the check)
+func messageTime(message *MessageAttributes) (time.Time, bool) {
+	if !message.RequestTimeParsed.IsZero() {
+		return message.RequestTimeParsed, true
+	}
+	if t, err := time.Parse(time.RFC3339, message.RequestTime); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+// validityPattern is the validity period of the rule as shown in the trace. example: "2026-11-01..2026-11-30"
+func validityPattern(rule *Rule) string {
+	return rule.ValidFrom + ".." + rule.ValidUntil
+}
+
+// RulesWithTag returns the indices of the rules with the tag (regardless of case)
+func RulesWithTag(rules *Rules, tag string) []int {
+	indices := []int{}
+	for i, rule := range rules.Rules {
+		for _, t := range rule.Tags {
+			if strings.EqualFold(t, tag) {
+				indices = append(indices, i)
+				break
+			}
+		}
+	}
+	return indices
+}
+
+// RulesOfOwner returns the indices of the rules of the owner (regardless of case)
+func RulesOfOwner(rules *Rules, owner string) []int {
+	indices := []int{}
+	for i, rule := range rules.Rules {
+		if strings.EqualFold(rule.Owner, owner) {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+// RulesExpiringWithin returns the indices of the rules whose validity period ends within the number of days from the time
+// (rules that already expired are not included)
+func RulesExpiringWithin(rules *Rules, now time.Time, days int) []int {
+	indices := []int{}
+	end := now.AddDate(0, 0, days)
+	for i, rule := range rules.Rules {
+		if !rule.ValidUntilTime.IsZero() && rule.ValidUntilTime.After(now) && !rule.ValidUntilTime.After(end) {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
@@ -0,0 +1,102 @@
+package MAPL_engine
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRuleMetadataQueries(t *testing.T) {
+	rules, err := ParseRulesFromFile("../examples/rules_metadata.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 11, 20, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		indices  []int
+		expected []int
+	}{
+		{"tag books", RulesWithTag(rules, "books"), []int{0, 1}},
+		{"tag Temporary", RulesWithTag(rules, "Temporary"), []int{1}},
+		{"tag none", RulesWithTag(rules, "none"), []int{}},
+		{"owner books-team", RulesOfOwner(rules, "books-team"), []int{0, 2}},
+		{"expiring within 10 days", RulesExpiringWithin(rules, now, 10), []int{}},
+		{"expiring within 11 days (the end of the date of validUntil)", RulesExpiringWithin(rules, now, 11), []int{1}},
+		{"expiring within 60 days", RulesExpiringWithin(rules, now, 60), []int{1, 2}},
+		{"expiring after the end", RulesExpiringWithin(rules, now.AddDate(0, 1, 0), 60), []int{2}},
+	}
+	for _, test := range tests {
+		if !reflect.DeepEqual(test.indices, test.expected) {
+			t.Errorf("%v: expected %v, got %v", test.name, test.expected, test.indices)
+		}
+	}
+
+	// the legacy reader parses the validity period too
+	legacy := YamlReadRulesFromFile("../examples/rules_metadata.yaml")
+	if !legacy.Rules[2].ValidUntilTime.Equal(rules.Rules[2].ValidUntilTime) || legacy.Rules[1].ValidFrom != "2026-11-01" {
+		t.Errorf("unexpected validity period of the legacy reader: %+v", legacy.Rules[1])
+	}
+}
+
+func TestRuleValidity(t *testing.T) {
+	rule := strings.Replace(strictRules, "    decision: allow", "    decision: allow\n    validFrom: 2026-11-01\n    validUntil: \"2026-11-30T12:00:00Z\"", 1)
+	rules, err := ParseRules([]byte(rule))
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := MessageAttributes{SourceService: "A.my_namespace", DestinationService: "B.my_namespace", ContextProtocol: "http", ContextType: "httpPath",
+		RequestPath: "/books/1", RequestMethod: "GET"}
+	for requestTime, expected := range map[string]int{
+		"2026-10-31T23:59:59Z": DEFAULT,
+		"2026-11-01T00:00:00Z": ALLOW,
+		"2026-11-30T12:00:00Z": ALLOW,
+		"2026-11-30T12:00:01Z": DEFAULT,
+		"":                     DEFAULT, // a message without a request time (regardless of the time of the check)
+	} {
+		message := message
+		message.RequestTime = requestTime
+		if err := addTimeInfoToMessage(&message); err != nil {
+			t.Fatal(err)
+		}
+		decision, _, _, _, _, trace := CheckWithTrace(&message, rules)
+		if decision != expected {
+			t.Errorf("%v: expected decision %v, got %v", requestTime, expected, decision)
+		}
+		stage := trace.Rules[0].Stages[0]
+		if stage.Stage != "validity" || stage.Match != (expected == ALLOW) || stage.MessageValue != requestTime {
+			t.Errorf("%v: unexpected trace stage %+v", requestTime, stage)
+		}
+	}
+
+	// rules without a validity period apply to messages without a request time
+	plain, _ := ParseRules([]byte(strictRules))
+	if decision, _, _, _, _ := Check(&message, plain); decision != ALLOW {
+		t.Errorf("expected allow by the rule without a validity period, got %v", decision)
+	}
+
+	// the hash changes with the validity period (and not with the other metadata)
+	withMetadata := strings.Replace(strictRules, "    decision: allow", "    decision: allow\n    owner: team\n    tags: [a]", 1)
+	metadata, _ := ParseRules([]byte(withMetadata))
+	if RuleMD5Hash(plain.Rules[0]) != RuleMD5Hash(metadata.Rules[0]) || RuleMD5Hash(plain.Rules[0]) == RuleMD5Hash(rules.Rules[0]) {
+		t.Errorf("unexpected hashes of the rules with metadata")
+	}
+
+	tests := []struct {
+		old, new string
+		path     string
+	}{
+		{"    decision: allow", "    decision: allow\n    validFrom: 2026-13-01", "rules[0].validFrom"},
+		{"    decision: allow", "    decision: allow\n    validUntil: tomorrow", "rules[0].validUntil"},
+		{"    decision: allow", "    decision: allow\n    validFrom: 2026-12-01\n    validUntil: 2026-11-30", "rules[0].validUntil"},
+	}
+	for _, test := range tests {
+		_, err := ParseRules([]byte(strings.Replace(strictRules, test.old, test.new, 1)))
+		ruleErr, ok := err.(*RuleError)
+		if !ok || !errors.Is(err, ErrInvalidValidity) || ruleErr.Path != test.path {
+			t.Errorf("%v: expected an invalid validity error, got %v", test.new, err)
+		}
+	}
+}
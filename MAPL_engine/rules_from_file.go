@@ -11,20 +11,30 @@ import (
 	"crypto/md5"
 	"fmt"
 	"sort"
+	"errors"
+
+	yamlv3 "gopkg.in/yaml.v3"
 )
 
 // YamlReadRulesFromString function reads rules from a yaml string
 func YamlReadRulesFromString(yamlString string) Rules {
 
+	data, upgraded, err := upgradeRulesYaml([]byte(yamlString)) // version 1 rules are upgraded to the current version
+	if err != nil {
+		panic(err)
+	}
+
 	var rules Rules
-	err := yaml.Unmarshal([]byte(yamlString), &rules)
+	err = yaml.Unmarshal(data, &rules)
 	if err != nil {
 		log.Fatalf("error: %v", err)
 	}
+	if upgraded {
+		rules.Version = CurrentRulesVersion
+	}
 
-	flag, outputString := IsNumberOfFieldsEqual(rules, yamlString)
-	if flag == false {
-		panic("number of fields in rules does not match number of fields in yaml file:\n" + outputString)
+	if ruleErr := unknownRuleField([]byte(yamlString), &rules); ruleErr != nil {
+		panic(ruleErr)
 	}
 	ConvertFieldsToRegexManyRules(&rules)
 	//testFieldsForIP(&rules)
@@ -44,20 +54,157 @@ func YamlReadRulesFromFile(filename string) Rules {
 }
 
 
+// ParseRules function reads rules from yaml data. Unlike YamlReadRulesFromString it does not panic on bad input.
+// The returned error is a *RuleError with the rule index, rule_id, path of the field and its line and column in the yaml data.
+// The data must have one yaml document (an ErrMultipleDocuments error otherwise). Files with several documents are read by LoadBundle.
+func ParseRules(data []byte) (*Rules, error) {
+
+	var root yamlv3.Node
+	err := yamlv3.Unmarshal(data, &root)
+	if err != nil {
+		line := yamlErrorLine(err.Error())
+		return nil, &RuleError{RuleIndex: yamlItemIndexAtLine(&root, "rules", line), Line: line, Err: fmt.Errorf("%w: %v", ErrInvalidYaml, err)}
+	}
+	documents, err := yamlDocuments(data) // yaml.v3 Unmarshal reads only the first document
+	if err != nil {
+		return nil, &RuleError{RuleIndex: -1, Line: yamlErrorLine(err.Error()), Err: fmt.Errorf("%w: %v", ErrInvalidYaml, err)}
+	}
+	if len(documents) > 1 {
+		top := yamlTopNode(documents[1])
+		err = fmt.Errorf("%w: %v documents with rules (read them with LoadBundle)", ErrMultipleDocuments, len(documents))
+		return nil, &RuleError{RuleIndex: -1, Line: top.Line, Column: top.Column, Err: err}
+	}
+	if len(documents) == 1 {
+		root = *documents[0]
+	}
+	return parseRulesNode(&root)
+}
+
+// parseRulesNode reads the rules of a yaml document (see ParseRules)
+func parseRulesNode(root *yamlv3.Node) (*Rules, error) {
+	upgraded, ruleErr := upgradeRulesNode(root) // version 1 rules are upgraded to the current version
+	if ruleErr != nil {
+		return nil, ruleErr
+	}
+
+	var rules Rules
+	if len(root.Content) > 0 {
+		err := root.Decode(&rules)
+		if err != nil {
+			errStr := yamlDecodeErrors(err)[0]
+			line := yamlErrorLine(errStr)
+			ruleIndex := yamlItemIndexAtLine(root, "rules", line)
+			path := "rules"
+			if ruleIndex >= 0 {
+				path = fmt.Sprintf("rules[%v]", ruleIndex)
+			}
+			return nil, &RuleError{RuleIndex: ruleIndex, Path: path, Line: line, Column: yamlColumnAtLine(root, line), Err: fmt.Errorf("%w: %v", ErrInvalidYaml, errStr)}
+		}
+	}
+
+	if upgraded {
+		rules.Version = CurrentRulesVersion
+	}
+
+	path, line, column, err := yamlUnknownField(root, &rules)
+	if err != nil {
+		return nil, newUnknownRuleFieldError(root, &rules, path, line, column, err)
+	}
+
+	for i := range rules.Rules {
+		err = convertRule(&rules.Rules[i])
+		if err != nil {
+			return nil, newRuleError(root, i, rules.Rules[i].RuleID, err)
+		}
+	}
+
+	return &rules, nil
+}
+
+// ParseRulesFromFile function reads rules from a yaml file. See ParseRules.
+func ParseRulesFromFile(filename string) (*Rules, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return ParseRules(data)
+}
+
+// convertRule converts the rule's string fields into regular expressions, ints, floats etc. to be used later in the Check function
+func convertRule(rule *Rule) error {
+	err := convertFieldsToRegex(rule)
+	if err != nil {
+		return err
+	}
+	return convertConditionStringToIntFloatRegex(rule)
+}
+
+// unknownRuleField returns a *RuleError if a key in the yaml data is not a field of the rules (nil if all of the keys are known).
+// It is used by the functions that decode the rules with yaml.v2.
+func unknownRuleField(data []byte, rules *Rules) *RuleError {
+	var root yamlv3.Node
+	err := yamlv3.Unmarshal(data, &root)
+	if err != nil {
+		return &RuleError{RuleIndex: -1, Line: yamlErrorLine(err.Error()), Err: fmt.Errorf("%w: %v", ErrInvalidYaml, err)}
+	}
+	if _, ruleErr := upgradeRulesNode(&root); ruleErr != nil {
+		return ruleErr
+	}
+	path, line, column, err := yamlUnknownField(&root, rules)
+	if err != nil {
+		return newUnknownRuleFieldError(&root, rules, path, line, column, err)
+	}
+	return nil
+}
+
+// newUnknownRuleFieldError returns the error of an unknown key with the rule it is in
+func newUnknownRuleFieldError(root *yamlv3.Node, rules *Rules, path string, line, column int, err error) *RuleError {
+	ruleIndex := yamlItemIndexAtLine(root, "rules", line)
+	ruleID := ""
+	if ruleIndex >= 0 && ruleIndex < len(rules.Rules) {
+		ruleID = rules.Rules[ruleIndex].RuleID
+	}
+	return &RuleError{RuleIndex: ruleIndex, RuleID: ruleID, Path: path, Line: line, Column: column, Err: err}
+}
+
+// newRuleError adds the rule details and the position in the yaml input to the error of one of the rule's fields
+func newRuleError(root *yamlv3.Node, ruleIndex int, ruleID string, err error) *RuleError {
+	path := fmt.Sprintf("rules[%v]", ruleIndex)
+	if fErr, ok := err.(*fieldError); ok {
+		path += "." + fErr.path
+		err = fErr.err
+	}
+	line, column := yamlPosition(root, path)
+	return &RuleError{RuleIndex: ruleIndex, RuleID: ruleID, Path: path, Line: line, Column: column, Err: err}
+}
+
 //YamlReadOneRule function reads one rule from yaml string
 func YamlReadOneRule(yamlString string) Rule {
 
+	var root yamlv3.Node
+	err := yamlv3.Unmarshal([]byte(yamlString), &root)
+	if err != nil {
+		panic(&RuleError{RuleIndex: -1, Line: yamlErrorLine(err.Error()), Err: fmt.Errorf("%w: %v", ErrInvalidYaml, err)})
+	}
+	data := []byte(yamlString)
+	if top := yamlTopNode(&root); isVersion1Rule(top) { // a version 1 rule is upgraded to the current version
+		upgradeRuleNode(top)
+		data, err = yamlv3.Marshal(&root)
+		if err != nil {
+			panic(err)
+		}
+	}
+
 	var rule Rule
-	err := yaml.Unmarshal([]byte(yamlString), &rule)
+	err = yaml.Unmarshal(data, &rule)
 	if err != nil {
 		log.Fatalf("error: %v", err)
 	}
 	//fmt.Printf("---values found:\n%+v\n\n", rule)
 
-	flag, outputString := IsNumberOfFieldsEqual(rule, yamlString)
-
-	if flag == false {
-		panic("number of fields in rule does not match number of fields in yaml file:\n" + outputString)
+	path, line, column, err := yamlUnknownField(&root, &rule)
+	if err != nil {
+		panic(&RuleError{RuleIndex: -1, Path: path, Line: line, Column: column, Err: err})
 	}
 	return rule
 }
@@ -95,17 +242,73 @@ func ConvertFieldsToRegexManyRules(rules *Rules) {
 // convertFieldsToRegex converts some rule fields into regular expressions to be used later.
 // This enables use of wildcards in the sender, receiver names, etc...
 func ConvertFieldsToRegex(rule *Rule) {
+	err := convertFieldsToRegex(rule)
+	if err != nil {
+		panic(err.Error())
+	}
+}
 
-		rule.Sender.SenderList = ConvertStringToExpandedSenderReceiver(rule.Sender.SenderName,rule.Sender.SenderType)
-		rule.Receiver.ReceiverList = ConvertStringToExpandedSenderReceiver(rule.Receiver.ReceiverName,rule.Receiver.ReceiverType)
+// convertFieldsToRegex is the error-returning version of ConvertFieldsToRegex
+func convertFieldsToRegex(rule *Rule) error {
 
-		rule.OperationRegex = regexp.MustCompile(ConvertOperationStringToRegex(rule.Operation)).Copy()  // a special case of regex for operations to support CRUD
+	senderList, err := convertStringToExpandedSenderReceiver(rule.Sender.SenderName, rule.Sender.SenderType)
+	if errors.Is(err, ErrUnsupportedType) {
+		return &fieldError{"sender.senderType", err}
+	}
+	if err != nil {
+		return &fieldError{"sender.senderName", err}
+	}
+	rule.Sender.SenderList = senderList
 
-		re := regexp.MustCompile(ConvertStringToRegex(rule.Resource.ResourceName))
-		rule.Resource.ResourceNameRegex = re.Copy()
+	receiverList, err := convertStringToExpandedSenderReceiver(rule.Receiver.ReceiverName, rule.Receiver.ReceiverType)
+	if errors.Is(err, ErrUnsupportedType) {
+		return &fieldError{"receiver.receiverType", err}
+	}
+	if err != nil {
+		return &fieldError{"receiver.receiverName", err}
+	}
+	rule.Receiver.ReceiverList = receiverList
+
+	re, err := regexp.Compile(ConvertOperationStringToRegex(rule.Operation)) // a special case of regex for operations to support CRUD
+	if err != nil {
+		return &fieldError{"operation", fmt.Errorf("%w: %q", ErrInvalidPattern, rule.Operation)}
+	}
+	rule.OperationRegex = re.Copy()
+
+	re, err = regexp.Compile("(?i)" + ConvertStringToRegex(rule.Protocol)) // the protocol is compared regardless of case
+	if err != nil {
+		return &fieldError{"protocol", fmt.Errorf("%w: %q", ErrInvalidPattern, rule.Protocol)}
+	}
+	rule.ProtocolRegex = re.Copy()
 
- 	//fmt.Printf("%+v\n",rules)
-	//fmt.Println("-------------")
+	// rules with protocol "*" used to skip the resource. an empty resource of such a rule is the same as "*" (any resource)
+	resourceType, resourceName := rule.Resource.ResourceType, rule.Resource.ResourceName
+	if rule.Protocol == "*" {
+		if resourceType == "" {
+			resourceType = "*"
+		}
+		if resourceName == "" {
+			resourceName = "*"
+		}
+	}
+
+	re, err = regexp.Compile(ConvertStringToRegex(resourceType))
+	if err != nil {
+		return &fieldError{"resource.resourceType", fmt.Errorf("%w: %q", ErrInvalidPattern, rule.Resource.ResourceType)}
+	}
+	rule.Resource.ResourceTypeRegex = re.Copy()
+
+	re, err = regexp.Compile(ConvertStringToRegex(resourceName))
+	if err != nil {
+		return &fieldError{"resource.resourceName", fmt.Errorf("%w: %q", ErrInvalidPattern, rule.Resource.ResourceName)}
+	}
+	rule.Resource.ResourceNameRegex = re.Copy()
+
+	err = convertMode(rule)
+	if err != nil {
+		return err
+	}
+	return convertValidity(rule)
 }
 
 // convertStringToRegex function converts one string to regex. Remove spaces, handle special characters and wildcards.
@@ -135,21 +338,52 @@ func ConvertStringToRegex(str_in string) string{
 }
 
 func ConvertStringToExpandedSenderReceiver(str_in string,type_in string) []ExpandedSenderReceiver{
+	output, err := convertStringToExpandedSenderReceiver(str_in, type_in)
+	if err != nil {
+		panic(err.Error())
+	}
+	return output
+}
+
+// convertStringToExpandedSenderReceiver is the error-returning version of ConvertStringToExpandedSenderReceiver
+func convertStringToExpandedSenderReceiver(str_in string,type_in string) ([]ExpandedSenderReceiver, error){
 	var output []ExpandedSenderReceiver
 
-	str_list := strings.Split(str_in, ",")
+	if !isMethodInList(type_in, senderReceiverTypes) {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedType, type_in)
+	}
+
+	// the list is separated by ';' (or by ',' except for label selectors that use ',' between requirements)
+	var str_list []string
+	if type_in == "labels" {
+		str_list = strings.Split(str_in, ";")
+	} else {
+		str_list = strings.FieldsFunc(str_in, func(r rune) bool { return r == ';' || r == ',' })
+		if len(str_list) == 0 {
+			str_list = []string{str_in}
+		}
+	}
 	for _, str := range(str_list) {
 		var e ExpandedSenderReceiver
 		e.Name = str
 		//e.IsIP,e.IsCIDR,e.IP,e.CIDR=isIpCIDR(str)
 		e.Type = type_in
+		if type_in=="labels"{
+			selector, err := ParseLabelSelector(str)
+			if err != nil {
+				return nil, err
+			}
+			e.Selector = selector
+			output=append(output,e)
+			continue
+		}
 		if type_in=="subnet"{
 			if str=="*"{
 				str="0.0.0.0/0"
 			}
 			e.IsIP,e.IsCIDR,e.IP, e.CIDR = isIpCIDR(str)
 			if !e.IsIP && !e.IsCIDR{
-				panic("Type is 'subnet' but value is not an IP or CIDR")
+				return nil, fmt.Errorf("%w: %q", ErrNotIPOrCIDR, str)
 			}
 		}
 		str = strings.Replace(str, " ", "", -1)    // remove spaces
@@ -161,14 +395,26 @@ func ConvertStringToExpandedSenderReceiver(str_in string,type_in string) []Expan
 		str = strings.Replace(str, "/", "\\/", -1)
 		str = "^" + str + "$" // force full string
 
-		e.Regexp=regexp.MustCompile(str).Copy()
+		re, err := regexp.Compile(str)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidPattern, e.Name)
+		}
+		e.Regexp=re.Copy()
 
 		output=append(output,e)
 	}
-	return output
+	return output, nil
 }
 
 
+// the types of senders and receivers:
+// service: the service name (with wildcards), "*": same as service
+// subnet: IP or CIDR
+// namespace: the namespace of the workload (with wildcards)
+// principal: the principal of the workload, for example a SPIFFE ID (with wildcards)
+// labels: a kubernetes-style label selector of the workload's labels
+var senderReceiverTypes = []string{"service", "*", "subnet", "namespace", "principal", "labels"}
+
 // convertOperationStringToRegex function converts the operations string to regex.
 // this is a special case of convertStringToRegex
 func ConvertOperationStringToRegex(str_in string) string{
@@ -178,9 +424,9 @@ func ConvertOperationStringToRegex(str_in string) string{
 	case "*":
 		str_out=".*"
 	case "write", "WRITE":
-		str_out="(^POST$|^PUT$|^DELETE$)" // we cannot translate to ".*" because then rules of type "write:block" would apply to all messages.
+		str_out="(^POST$|^PUT$|^DELETE$|^PRODUCE$)" // we cannot translate to ".*" because then rules of type "write:block" would apply to all messages.
 	case "read", "READ":
-		str_out="(^GET$|^HEAD$|^OPTIONS$|^TRACE$|^read$|^READ$)"
+		str_out="(^GET$|^HEAD$|^OPTIONS$|^TRACE$|^CONSUME$|^read$|^READ$)"
 	default:
 		str_out=ConvertStringToRegex(str_in)
 	}
@@ -199,6 +445,14 @@ func ConvertConditionStringToIntFloatRegexManyRules(rules *Rules) {
 // ConvertConditionStringToIntFloatRegexManyRules convert values in strings in the conditions to integers, floats and regex
 // (or keep them default in case of failure)
 func ConvertConditionStringToIntFloatRegex(r *Rule) {
+	err := convertConditionStringToIntFloatRegex(r)
+	if err != nil {
+		panic(err.Error())
+	}
+}
+
+// convertConditionStringToIntFloatRegex is the error-returning version of ConvertConditionStringToIntFloatRegex
+func convertConditionStringToIntFloatRegex(r *Rule) error {
 	for i_dnf, andConditions:=range(r.DNFConditions) {
 		for i_and, condition := range (andConditions.ANDConditions) {
 
@@ -218,8 +472,8 @@ func ConvertConditionStringToIntFloatRegex(r *Rule) {
 			re,err = regexp.Compile(ConvertStringToRegex(condition.Value))
 			if err == nil{
 				r.DNFConditions[i_dnf].ANDConditions[i_and].ValueStringRegex = re.Copy() // this is used in EQ,NEQ
-			}else{
-				panic("condition.Value could not be converted to regex")
+			}else if !isLabelSelectorAttribute(condition.Attribute){ // label selectors are parsed in validateConditionMethod
+				return conditionError(i_dnf, i_and, "value", fmt.Errorf("%w: %q", ErrInvalidPattern, condition.Value))
 			}
 
 			/*t, err := time.Parse(time.RFC3339,condition.Value)
@@ -232,7 +486,7 @@ func ConvertConditionStringToIntFloatRegex(r *Rule) {
 				i1:=strings.Index(condition.Attribute,"[")+1
 				i2:=strings.Index(condition.Attribute,"]")
 				if i2 < len(condition.Attribute)-1{
-					panic("senderLabel has a wrong format")
+					return conditionError(i_dnf, i_and, "attribute", fmt.Errorf("%w: %q", ErrInvalidLabel, condition.Attribute))
 				}
 				r.DNFConditions[i_dnf].ANDConditions[i_and].AttributeSenderLabelKey=condition.Attribute[i1:i2]
 				r.DNFConditions[i_dnf].ANDConditions[i_and].Attribute="senderLabel"
@@ -243,7 +497,7 @@ func ConvertConditionStringToIntFloatRegex(r *Rule) {
 				i1:=strings.Index(condition.Attribute,"[")+1
 				i2:=strings.Index(condition.Attribute,"]")
 				if i2 < len(condition.Attribute)-1{
-					panic("receiverLabel has a wrong format")
+					return conditionError(i_dnf, i_and, "attribute", fmt.Errorf("%w: %q", ErrInvalidLabel, condition.Attribute))
 				}
 				r.DNFConditions[i_dnf].ANDConditions[i_and].AttributeReceiverLabelKey=condition.Attribute[i1:i2]
 				r.DNFConditions[i_dnf].ANDConditions[i_and].Attribute="receiverLabel"
@@ -255,19 +509,109 @@ func ConvertConditionStringToIntFloatRegex(r *Rule) {
 				i1:=strings.Index(condition.Value,"[")+1
 				i2:=strings.Index(condition.Value,"]")
 				if i2 < len(condition.Value)-1{
-					panic("value receiverLabel has a wrong format")
+					return conditionError(i_dnf, i_and, "value", fmt.Errorf("%w: %q", ErrInvalidLabel, condition.Value))
 				}
 				r.DNFConditions[i_dnf].ANDConditions[i_and].ValueReceiverLabelKey=condition.Value[i1:i2]
 				r.DNFConditions[i_dnf].ANDConditions[i_and].Value="receiverLabel"
 				r.DNFConditions[i_dnf].ANDConditions[i_and].OriginalValue=condition.Value // used in hash
 			}
+
+			err = validateConditionMethod(&r.DNFConditions[i_dnf].ANDConditions[i_and])
+			if err != nil {
+				if fErr, ok := err.(*fieldError); ok {
+					return conditionError(i_dnf, i_and, fErr.path, fErr.err)
+				}
+				return conditionError(i_dnf, i_and, "method", err)
+			}
 		}
 	}
+	return nil
+}
+
+var numericMethods = []string{"EQ", "NEQ", "NE", "LT", "LE", "GT", "GE", "IN", "NIN", "BETWEEN", "NBETWEEN", "EX", "NEX"}
+var stringMethods = []string{"EQ", "NEQ", "NE", "RE", "NRE", "IN", "NIN", "EX", "NEX"}
+var ipMethods = []string{"EQ", "NEQ", "NE", "IN_CIDR", "NOT_IN_CIDR", "EX", "NEX"}
+var labelToLabelMethods = []string{"EQ", "NEQ", "NE", "EX", "NEX"} // comparison of senderLabel[key1] to receiverLabel[key2]
+
+// validateConditionMethod tests that the condition's attribute is supported and that the method is supported for the attribute.
+// Methods are written in upper case or in lower case.
+// The attribute is resolved from the attribute registry and the value is converted to the attribute's type.
+func validateConditionMethod(c *Condition) error {
+	if strings.HasPrefix(c.Attribute, customAttributePrefix) { // the method and value are used by the custom function
+		return convertCustomCondition(c)
+	}
+
+	var methods []string
+	switch c.Attribute {
+	case "true", "TRUE", "false", "FALSE": // the method is not used
+		return nil
+	case "senderLabel":
+		if !c.AttributeIsSenderLabel {
+			return &fieldError{"attribute", fmt.Errorf("%w: %q", ErrInvalidLabel, c.Attribute)}
+		}
+		methods = stringMethods
+		if c.ValueIsReceiverLabel {
+			methods = labelToLabelMethods
+		}
+	case "receiverLabel":
+		if !c.AttributeIsReceiverLabel {
+			return &fieldError{"attribute", fmt.Errorf("%w: %q", ErrInvalidLabel, c.Attribute)}
+		}
+		methods = stringMethods
+	case "senderLabels", "receiverLabels":
+		methods = selectorMethods
+	default:
+		c.attribute = lookupAttribute(c.Attribute)
+		if c.attribute == nil {
+			return &fieldError{"attribute", fmt.Errorf("%w: %q", ErrUnsupportedAttribute, c.Attribute)}
+		}
+		methods = c.attribute.Methods()
+	}
+	if !isMethodInList(c.Method, methods) {
+		return fmt.Errorf("%w: %q (supported methods: %v)", ErrUnsupportedMethod, c.Method, strings.Join(methods, ","))
+	}
+	if isMethodInList(c.Method, []string{"RE", "NRE"}) && c.ValueRegex == nil {
+		return &fieldError{"value", fmt.Errorf("%w: %q is not a regular expression", ErrInvalidValue, c.Value)}
+	}
+	if c.attribute != nil {
+		err := c.attribute.convertValue(c)
+		if err != nil {
+			return &fieldError{"value", err}
+		}
+	}
+	if isLabelSelectorAttribute(c.Attribute) {
+		err := convertSelectorCondition(c)
+		if err != nil {
+			return &fieldError{"value", err}
+		}
+	}
+	return nil
+}
+
+// isMethodInList tests if the method (in upper case or in lower case) is in the list
+func isMethodInList(method string, methods []string) bool {
+	for _, m := range methods {
+		if method == m || method == strings.ToLower(m) {
+			return true
+		}
+	}
+	return false
+}
+
+// conditionError returns the error with the path of the condition's field
+func conditionError(i_dnf, i_and int, field string, err error) error {
+	return &fieldError{fmt.Sprintf("DNFconditions[%v].ANDconditions[%v].%v", i_dnf, i_and, field), err}
 }
 
 func RuleMD5Hash(rule Rule) (md5hash string){
 	strMainPart := strings.ToLower(rule.Decision)+"-<"+strings.ToLower(rule.Sender.SenderType)+":"+rule.Sender.SenderName+">-<"+strings.ToLower(rule.Receiver.ReceiverType)+
 		":"+rule.Receiver.ReceiverName+">-"+strings.ToLower(rule.Operation)+"-"+strings.ToLower(rule.Protocol)+"-<"+rule.Resource.ResourceType+"-"+rule.Resource.ResourceName+">"
+	if rule.ValidFrom != "" || rule.ValidUntil != "" { // the validity period changes the decisions (the other metadata does not)
+		strMainPart += "-<valid:" + rule.ValidFrom + ".." + rule.ValidUntil + ">"
+	}
+	if IsAuditRule(&rule) { // audit rules do not change the decisions
+		strMainPart += "-<mode:audit>"
+	}
 
 	dnfStrings:= []string{}
 	for _, andConditions := range rule.DNFConditions{
@@ -0,0 +1,141 @@
+package MAPL_engine
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestSenderReceiverLists tests the separators of the lists of senders and receivers: ';' (as in the spec) and ',' (the separator of earlier versions)
+func TestSenderReceiverLists(t *testing.T) {
+	tests := []struct {
+		name, senderType string
+		expected         []string
+	}{
+		{"A.my_namespace;B.*", "service", []string{"A.my_namespace", "B.*"}},
+		{"A.my_namespace,B.*", "service", []string{"A.my_namespace", "B.*"}},
+		{"A.my_namespace;B.*,C", "*", []string{"A.my_namespace", "B.*", "C"}},
+		{"10.0.0.0/8,10.1.1.1;10.2.0.0/16", "subnet", []string{"10.0.0.0/8", "10.1.1.1", "10.2.0.0/16"}},
+		{"app=web,tier in (front,edge);app=db", "labels", []string{"app=web,tier in (front,edge)", "app=db"}},
+		{"A.my_namespace", "service", []string{"A.my_namespace"}},
+	}
+	for _, test := range tests {
+		list, err := convertStringToExpandedSenderReceiver(test.name, test.senderType)
+		if err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		}
+		names := []string{}
+		for _, e := range list {
+			names = append(names, e.Name)
+		}
+		if !reflect.DeepEqual(names, test.expected) {
+			t.Errorf("%v: expected %v, got %v", test.name, test.expected, names)
+		}
+	}
+
+	// both separators match each of the services of the list
+	for _, name := range []string{"A.my_namespace;B.*", "A.my_namespace,B.*"} {
+		rule := Rule{Sender: Sender{SenderName: name, SenderType: "service"}, Receiver: Receiver{ReceiverName: "*", ReceiverType: "*"},
+			Protocol: "*", Operation: "*", Decision: "allow"}
+		if err := convertRule(&rule); err != nil {
+			t.Fatal(err)
+		}
+		for service, expected := range map[string]bool{"A.my_namespace": true, "B.other_namespace": true, "C.my_namespace": false} {
+			if TestSender(&rule, &MessageAttributes{SourceService: service}) != expected {
+				t.Errorf("%v: expected %v for the sender %v", name, expected, service)
+			}
+		}
+	}
+}
+
+// TestRuleConversionErrors tests the errors of the conversion of the rules: the sentinel error, the rule and the path, line and column of the field
+func TestRuleConversionErrors(t *testing.T) {
+	data := `rules:
+  - rule_id: r1
+    sender:
+      senderName: "A.my_namespace"
+      senderType: service
+    receiver:
+      receiverName: "B.my_namespace"
+      receiverType: service
+    protocol: http
+    resource:
+      resourceType: httpPath
+      resourceName: "/books/*"
+    operation: GET
+    DNFconditions:
+      - ANDconditions:
+        - attribute: payloadSize
+          method: GT
+          value: 1024
+    decision: allow
+`
+	if _, err := ParseRules([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		old, new string
+		err      error
+		path     string
+		line     int
+		column   int
+	}{
+		{"senderType: service", "senderType: pod", ErrUnsupportedType, "rules[0].sender.senderType", 5, 19},
+		{"receiverType: service", "receiverType: SERVICE", ErrUnsupportedType, "rules[0].receiver.receiverType", 8, 21},
+		{"senderType: service", "senderType: subnet", ErrNotIPOrCIDR, "rules[0].sender.senderName", 4, 19},
+		{"receiverType: service", "receiverType: subnet", ErrNotIPOrCIDR, "rules[0].receiver.receiverName", 7, 21},
+		{"protocol: http", `protocol: "("`, ErrInvalidPattern, "rules[0].protocol", 9, 15},
+		{`resourceName: "/books/*"`, `resourceName: "/books/("`, ErrInvalidPattern, "rules[0].resource.resourceName", 12, 21},
+		{"attribute: payloadSize", "attribute: payload_size", ErrUnsupportedAttribute, "rules[0].DNFconditions[0].ANDconditions[0].attribute", 16, 22},
+		{"method: GT", "method: RE", ErrUnsupportedMethod, "rules[0].DNFconditions[0].ANDconditions[0].method", 17, 19},
+		{"value: 1024", "value: 1k", ErrInvalidValue, "rules[0].DNFconditions[0].ANDconditions[0].value", 18, 18},
+	}
+	for _, test := range tests {
+		_, err := ParseRules([]byte(strings.Replace(data, test.old, test.new, 1)))
+		ruleErr, ok := err.(*RuleError)
+		if !ok {
+			t.Fatalf("%v: expected a *RuleError, got %v", test.new, err)
+		}
+		if !errors.Is(err, test.err) || ruleErr.RuleIndex != 0 || ruleErr.RuleID != "r1" || ruleErr.Path != test.path || ruleErr.Line != test.line || ruleErr.Column != test.column {
+			t.Errorf("%v: unexpected error: %v", test.new, err)
+		}
+	}
+}
+
+// TestMessageConversionErrors tests the errors of the conversion of the messages: the sentinel error, the message and the path, line and column of the field
+func TestMessageConversionErrors(t *testing.T) {
+	data := `messages:
+  - message_id: m0
+    sender_service: A.my_namespace
+  - message_id: m1
+    expected_decision: allow
+    request_time: "2026-11-01T10:00:00Z"
+    sender_labels: "{app:web,tier:front}"
+`
+	if _, err := ParseMessages([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		old, new string
+		err      error
+		path     string
+		line     int
+		column   int
+	}{
+		{"expected_decision: allow", "expected_decision: allowed", ErrInvalidDecision, "messages[1].expected_decision", 5, 24},
+		{`request_time: "2026-11-01T10:00:00Z"`, `request_time: "yesterday"`, ErrInvalidTime, "messages[1].request_time", 6, 19},
+		{`sender_labels: "{app:web,tier:front}"`, `sender_labels: "{app:web,tier"`, ErrInvalidLabelsJson, "messages[1].sender_labels", 7, 20},
+	}
+	for _, test := range tests {
+		_, err := ParseMessages([]byte(strings.Replace(data, test.old, test.new, 1)))
+		messageErr, ok := err.(*MessageError)
+		if !ok {
+			t.Fatalf("%v: expected a *MessageError, got %v", test.new, err)
+		}
+		if !errors.Is(err, test.err) || messageErr.MessageIndex != 1 || messageErr.MessageID != "m1" || messageErr.Path != test.path ||
+			messageErr.Line != test.line || messageErr.Column != test.column {
+			t.Errorf("%v: unexpected error: %v", test.new, err)
+		}
+	}
+}
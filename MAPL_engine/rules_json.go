@@ -0,0 +1,317 @@
+package MAPL_engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// the authored structures contain only the fields of the rules as written in the rules files (without the fields that are derived when the rules are read:
+// the expanded sender and receiver lists, the regular expressions, the parsed values of the conditions etc.).
+// They are used to read rules from json and to write rules to yaml and json (the json keys are the json tags of the Rule structure).
+
+type authoredRules struct {
+	Version string         `yaml:"version,omitempty" json:"Version,omitempty"`
+	Rules   []authoredRule `yaml:"rules" json:"Rules"`
+}
+
+type authoredRule struct {
+	RuleID        string                  `yaml:"rule_id,omitempty" json:"RuleID,omitempty"`
+	Description   string                  `yaml:"description,omitempty" json:"Description,omitempty"`
+	Owner         string                  `yaml:"owner,omitempty" json:"Owner,omitempty"`
+	Tags          []string                `yaml:"tags,omitempty" json:"Tags,omitempty"`
+	ValidFrom     string                  `yaml:"validFrom,omitempty" json:"ValidFrom,omitempty"`
+	ValidUntil    string                  `yaml:"validUntil,omitempty" json:"ValidUntil,omitempty"`
+	Sender        authoredSender          `yaml:"sender" json:"Sender"`
+	Receiver      authoredReceiver        `yaml:"receiver" json:"Receiver"`
+	Protocol      string                  `yaml:"protocol,omitempty" json:"Protocol,omitempty"`
+	Resource      authoredResource        `yaml:"resource" json:"Resource"`
+	Operation     string                  `yaml:"operation,omitempty" json:"Operation,omitempty"`
+	DNFConditions []authoredANDConditions `yaml:"DNFconditions,omitempty" json:"DNFConditions,omitempty"`
+	Decision      string                  `yaml:"decision,omitempty" json:"Decision,omitempty"`
+	Mode          string                  `yaml:"mode,omitempty" json:"Mode,omitempty"`
+}
+
+type authoredSender struct {
+	SenderName string `yaml:"senderName,omitempty" json:"SenderName,omitempty"`
+	SenderType string `yaml:"senderType,omitempty" json:"SenderType,omitempty"`
+}
+
+type authoredReceiver struct {
+	ReceiverName string `yaml:"receiverName,omitempty" json:"ReceiverName,omitempty"`
+	ReceiverType string `yaml:"receiverType,omitempty" json:"ReceiverType,omitempty"`
+}
+
+type authoredResource struct {
+	ResourceType string `yaml:"resourceType,omitempty" json:"ResourceType,omitempty"`
+	ResourceName string `yaml:"resourceName,omitempty" json:"ResourceName,omitempty"`
+}
+
+type authoredANDConditions struct {
+	ANDConditions []authoredCondition `yaml:"ANDconditions" json:"ANDConditions"`
+}
+
+type authoredCondition struct {
+	Attribute string `yaml:"attribute" json:"Attribute"`
+	Method    string `yaml:"method" json:"Method"`
+	Value     string `yaml:"value,omitempty" json:"Value,omitempty"`
+}
+
+// newAuthoredRule returns the authored fields of the rule. The attribute and value of the conditions are the original ones (as in RuleMD5Hash).
+func newAuthoredRule(rule *Rule) authoredRule {
+	a := authoredRule{
+		RuleID:      rule.RuleID,
+		Description: rule.Description,
+		Owner:       rule.Owner,
+		Tags:        rule.Tags,
+		ValidFrom:   rule.ValidFrom,
+		ValidUntil:  rule.ValidUntil,
+		Sender:      authoredSender{SenderName: rule.Sender.SenderName, SenderType: rule.Sender.SenderType},
+		Receiver:    authoredReceiver{ReceiverName: rule.Receiver.ReceiverName, ReceiverType: rule.Receiver.ReceiverType},
+		Protocol:    rule.Protocol,
+		Resource:    authoredResource{ResourceType: rule.Resource.ResourceType, ResourceName: rule.Resource.ResourceName},
+		Operation:   rule.Operation,
+		Decision:    rule.Decision,
+		Mode:        rule.Mode,
+	}
+	for _, andConditions := range rule.DNFConditions {
+		conditions := authoredANDConditions{ANDConditions: []authoredCondition{}}
+		for _, c := range andConditions.ANDConditions {
+			attribute := c.OriginalAttribute
+			if attribute == "" {
+				attribute = c.Attribute
+			}
+			value := c.OriginalValue
+			if value == "" {
+				value = c.Value
+			}
+			conditions.ANDConditions = append(conditions.ANDConditions, authoredCondition{Attribute: attribute, Method: c.Method, Value: value})
+		}
+		a.DNFConditions = append(a.DNFConditions, conditions)
+	}
+	return a
+}
+
+// rule returns a rule with the authored fields (the derived fields are added by convertRule)
+func (a *authoredRule) rule() Rule {
+	rule := Rule{
+		RuleID:      a.RuleID,
+		Description: a.Description,
+		Owner:       a.Owner,
+		Tags:        a.Tags,
+		ValidFrom:   a.ValidFrom,
+		ValidUntil:  a.ValidUntil,
+		Sender:      Sender{SenderName: a.Sender.SenderName, SenderType: a.Sender.SenderType},
+		Receiver:    Receiver{ReceiverName: a.Receiver.ReceiverName, ReceiverType: a.Receiver.ReceiverType},
+		Protocol:    a.Protocol,
+		Resource:    Resource{ResourceType: a.Resource.ResourceType, ResourceName: a.Resource.ResourceName},
+		Operation:   a.Operation,
+		Decision:    a.Decision,
+		Mode:        a.Mode,
+	}
+	for _, andConditions := range a.DNFConditions {
+		conditions := ANDConditions{}
+		for _, c := range andConditions.ANDConditions {
+			conditions.ANDConditions = append(conditions.ANDConditions, Condition{Attribute: c.Attribute, Method: c.Method, Value: c.Value})
+		}
+		rule.DNFConditions = append(rule.DNFConditions, conditions)
+	}
+	return rule
+}
+
+func newAuthoredRules(rules *Rules) authoredRules {
+	a := authoredRules{Version: rules.Version, Rules: []authoredRule{}}
+	for i := range rules.Rules {
+		a.Rules = append(a.Rules, newAuthoredRule(&rules.Rules[i]))
+	}
+	return a
+}
+
+// RulesToYaml writes the authored fields of the rules in the yaml format of the rules files (the fields that are derived when the rules are read are not written).
+// Reading the output with ParseRules returns the same rules.
+func RulesToYaml(rules *Rules) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yamlv3.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	err := encoder.Encode(newAuthoredRules(rules))
+	if err != nil {
+		return nil, err
+	}
+	err = encoder.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// RulesToJson writes the authored fields of the rules in json (the fields that are derived when the rules are read are not written).
+// Reading the output with ParseRulesJSON returns the same rules.
+func RulesToJson(rules *Rules) ([]byte, error) {
+	return json.MarshalIndent(newAuthoredRules(rules), "", "  ")
+}
+
+// ParseRulesJSON function reads rules from json data. The keys are the json tags of the rules structures (as written by RulesToJson). example:
+//
+//	{"Rules": [{"RuleID": "0", "Sender": {"SenderName": "A.*", "SenderType": "service"}, ..., "Decision": "allow"}]}
+//
+// The optional Version key is the current version (see CurrentRulesVersion). Keys that are not fields of the rules are errors. The returned error is a *RuleError as in ParseRules (the line and column are of the json data).
+func ParseRulesJSON(data []byte) (*Rules, error) {
+	rules, _, err := parseRulesJSON(data)
+	return rules, err
+}
+
+// parseRulesJSON reads rules from json data (see ParseRulesJSON) and returns the offsets of the rules in the data
+func parseRulesJSON(data []byte) (*Rules, []int64, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	jsonError := func(ruleIndex int, ruleID, path string, offset int64, err error) *RuleError {
+		line, column := jsonPosition(data, offset)
+		if ruleIndex >= 0 {
+			rulePath := fmt.Sprintf("Rules[%v]", ruleIndex)
+			if path != "" {
+				rulePath += "." + path
+			}
+			path = rulePath
+		}
+		return &RuleError{RuleIndex: ruleIndex, RuleID: ruleID, Path: path, Line: line, Column: column, Err: err}
+	}
+
+	// the rules are decoded one by one in order to report the index of the rule with the error
+	rules := Rules{}
+	offsets := []int64{}
+	token, err := decoder.Token()
+	if err != nil || token != json.Delim('{') {
+		return nil, nil, jsonError(-1, "", "", jsonSyntaxErrorOffset(data, 0), jsonDecodeError(err, "expected an object with the Rules list"))
+	}
+	for decoder.More() {
+		offset := decoder.InputOffset()
+		token, err = decoder.Token()
+		if err != nil {
+			return nil, nil, jsonError(-1, "", "", jsonSyntaxErrorOffset(data, decoder.InputOffset()), jsonDecodeError(err, ""))
+		}
+		if key, _ := token.(string); strings.EqualFold(key, "Version") { // version 1 is a yaml format. json rules are of the current version
+			offset = decoder.InputOffset()
+			token, err = decoder.Token()
+			if err != nil {
+				return nil, nil, jsonError(-1, "", "Version", jsonSyntaxErrorOffset(data, offset), jsonDecodeError(err, ""))
+			}
+			if token != CurrentRulesVersion {
+				return nil, nil, jsonError(-1, "", "Version", offset, fmt.Errorf("%w: %q (json rules are of version %v)", ErrUnsupportedVersion, fmt.Sprint(token), CurrentRulesVersion))
+			}
+			rules.Version = CurrentRulesVersion
+			continue
+		}
+		if key, _ := token.(string); !strings.EqualFold(key, "Rules") {
+			return nil, nil, jsonError(-1, "", fmt.Sprint(token), offset, unknownFieldError(fmt.Sprint(token), map[string]reflect.Type{"Version": nil, "Rules": nil}))
+		}
+		token, err = decoder.Token()
+		if err != nil || token != json.Delim('[') {
+			return nil, nil, jsonError(-1, "", "Rules", jsonSyntaxErrorOffset(data, offset), jsonDecodeError(err, "expected a list of rules"))
+		}
+		for decoder.More() {
+			ruleIndex := len(rules.Rules)
+			var raw json.RawMessage
+			err = decoder.Decode(&raw)
+			if err != nil {
+				return nil, nil, jsonError(ruleIndex, "", "", jsonSyntaxErrorOffset(data, decoder.InputOffset()), jsonDecodeError(err, ""))
+			}
+			start := decoder.InputOffset() - int64(len(raw))
+			a, path, offset, err := decodeAuthoredRule(raw)
+			if err != nil {
+				return nil, nil, jsonError(ruleIndex, a.RuleID, path, start+offset, err)
+			}
+			rules.Rules = append(rules.Rules, a.rule())
+			offsets = append(offsets, start)
+		}
+		if _, err = decoder.Token(); err != nil { // ']'
+			return nil, nil, jsonError(-1, "", "Rules", jsonSyntaxErrorOffset(data, decoder.InputOffset()), jsonDecodeError(err, ""))
+		}
+	}
+	if _, err = decoder.Token(); err != nil { // '}'
+		return nil, nil, jsonError(-1, "", "", jsonSyntaxErrorOffset(data, decoder.InputOffset()), jsonDecodeError(err, ""))
+	}
+	if _, err = decoder.Token(); err != io.EOF {
+		return nil, nil, jsonError(-1, "", "", jsonSyntaxErrorOffset(data, decoder.InputOffset()), jsonDecodeError(err, "data after the rules object"))
+	}
+
+	for i := range rules.Rules {
+		err = convertRule(&rules.Rules[i])
+		if err != nil {
+			return nil, nil, newRuleError(nil, i, rules.Rules[i].RuleID, err) // the path is of the fields in the rules files (example: rules[0].sender.senderType)
+		}
+	}
+	return &rules, offsets, nil
+}
+
+// ParseRulesJSONFromFile function reads rules from a json file. See ParseRulesJSON.
+func ParseRulesJSONFromFile(filename string) (*Rules, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return ParseRulesJSON(data)
+}
+
+// jsonDecodeError wraps the error of the json decoder. An unknown field is an ErrUnknownField error.
+func jsonDecodeError(err error, expected string) error {
+	if err == nil || err == io.EOF {
+		return fmt.Errorf("%w: %v", ErrInvalidJson, expected)
+	}
+	if str := err.Error(); strings.HasPrefix(str, "json: unknown field ") {
+		return fmt.Errorf("%w: %v", ErrUnknownField, strings.TrimPrefix(str, "json: unknown field "))
+	}
+	return fmt.Errorf("%w: %v", ErrInvalidJson, err)
+}
+
+// decodeAuthoredRule decodes one rule. On errors it returns the path of the field (relative to the rule) and the offset of the error in the rule's json data.
+func decodeAuthoredRule(data []byte) (authoredRule, string, int64, error) {
+	var a authoredRule
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&a)
+	if err == nil {
+		return a, "", 0, nil
+	}
+	if typeErr, ok := err.(*json.UnmarshalTypeError); ok {
+		return a, typeErr.Field, typeErr.Offset - 1, fmt.Errorf("%w: expected a %v, got %v", ErrInvalidJson, typeErr.Type, typeErr.Value)
+	}
+	err = jsonDecodeError(err, "")
+	if errors.Is(err, ErrUnknownField) { // the unknown key (as quoted in the error)
+		key := strings.TrimPrefix(err.Error(), ErrUnknownField.Error()+": ")
+		if i := bytes.Index(data, []byte(key+":")); i >= 0 {
+			return a, strings.Trim(key, "\""), int64(i), err
+		}
+		return a, strings.Trim(key, "\""), 0, err
+	}
+	return a, "", 0, err
+}
+
+// jsonSyntaxErrorOffset returns the offset of the syntax error in the json data (the offsets of the errors of the decoder are not of the whole data)
+func jsonSyntaxErrorOffset(data []byte, offset int64) int64 {
+	var v interface{}
+	if syntaxErr, ok := json.Unmarshal(data, &v).(*json.SyntaxError); ok {
+		return syntaxErr.Offset - 1
+	}
+	return offset
+}
+
+// jsonPosition returns the line and column of the offset in the data
+func jsonPosition(data []byte, offset int64) (line, column int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	for offset < int64(len(data)) && strings.ContainsRune(" \t\r\n,:", rune(data[offset])) { // the offset of the decoder may be before the separator of the next value
+		offset++
+	}
+	before := data[:offset]
+	line = bytes.Count(before, []byte("\n")) + 1
+	column = int(offset) - bytes.LastIndexByte(before, '\n')
+	return line, column
+}
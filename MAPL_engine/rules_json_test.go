@@ -0,0 +1,92 @@
+package MAPL_engine
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// TestRulesRoundTrip writes the rules of the examples to yaml and json and reads them back.
+// The rules that are read back have the same authored fields and the same decisions for the messages of the examples.
+func TestRulesRoundTrip(t *testing.T) {
+	for _, pair := range examplePairs {
+		rulesFilename, messagesFilename := pair[0], pair[1]
+		t.Run(rulesFilename[len("../examples/"):], func(t *testing.T) {
+			rules, err := ParseRulesFromFile(rulesFilename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			messages, err := ParseMessagesFromFile(messagesFilename)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			yamlData, err := RulesToYaml(rules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			jsonData, err := RulesToJson(rules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fromYaml, err := ParseRules(yamlData)
+			if err != nil {
+				t.Fatalf("%v\n%s", err, yamlData)
+			}
+			fromJson, err := ParseRulesJSON(jsonData)
+			if err != nil {
+				t.Fatalf("%v\n%s", err, jsonData)
+			}
+
+			for name, read := range map[string]*Rules{"yaml": fromYaml, "json": fromJson} {
+				if !reflect.DeepEqual(newAuthoredRules(rules), newAuthoredRules(read)) {
+					t.Errorf("%v: the rules changed in the round trip", name)
+				}
+				for i := range rules.Rules {
+					if RuleMD5Hash(rules.Rules[i]) != RuleMD5Hash(read.Rules[i]) {
+						t.Errorf("%v: the hash of rule #%v changed in the round trip", name, i)
+					}
+				}
+				report := CheckExpectations(messages, read)
+				if report.Failed > 0 {
+					t.Errorf("%v: %v", name, report.String())
+				}
+			}
+
+			yamlData2, _ := RulesToYaml(fromYaml)
+			jsonData2, _ := RulesToJson(fromJson)
+			if !bytes.Equal(yamlData, yamlData2) || !bytes.Equal(jsonData, jsonData2) {
+				t.Errorf("the output changed after the round trip")
+			}
+		})
+	}
+}
+
+func TestParseRulesJSONErrors(t *testing.T) {
+	tests := []struct {
+		data         string
+		err          error
+		ruleIndex    int
+		path         string
+		line, column int
+	}{
+		{`{"Rules": [{"RuleID": "0", "Recevier": {}}]}`, ErrUnknownField, 0, "Rules[0].Recevier", 1, 28},
+		{"{\n  \"Rules\": [\n    {\"RuleID\": \"0\"},\n    {\"RuleID\": 1}\n  ]\n}", ErrInvalidJson, 1, "Rules[1].RuleID", 4, 16},
+		{`{"Rules": [{"RuleID": "0",}]}`, ErrInvalidJson, 0, "Rules[0]", 1, 27},
+		{`{"rulez": []}`, ErrUnknownField, -1, "rulez", 1, 2},
+		{`[]`, ErrInvalidJson, -1, "", 1, 1},
+		{`{"Version": "1", "Rules": []}`, ErrUnsupportedVersion, -1, "Version", 1, 13},
+		{`{"Rules": [{"RuleID": "0", "Sender": {"SenderName": "A", "SenderType": "subnet"}}]}`, ErrNotIPOrCIDR, 0, "rules[0].sender.senderName", 0, 0},
+	}
+	for _, test := range tests {
+		_, err := ParseRulesJSON([]byte(test.data))
+		ruleErr, ok := err.(*RuleError)
+		if !ok {
+			t.Fatalf("%v: expected a *RuleError, got %v", test.data, err)
+		}
+		if !errors.Is(err, test.err) || ruleErr.RuleIndex != test.ruleIndex || ruleErr.Path != test.path || ruleErr.Line != test.line || ruleErr.Column != test.column {
+			t.Errorf("%v: unexpected error: %v", test.data, err)
+		}
+	}
+}
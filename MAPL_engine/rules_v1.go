@@ -0,0 +1,213 @@
+package MAPL_engine
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// CurrentRulesVersion is the version of the rules format of the Rule structure
+const CurrentRulesVersion = "2"
+
+// version 1 rules (see "MAPL versions" in docs/MAPL_SPEC.md) have sender and receiver strings and the protocol in the resource. example:
+//
+//	rules:
+//	  - rule_id: 0
+//	    sender: "A.my_namespace"
+//	    receiver: "B.my_namespace"
+//	    resource:
+//	      resourceProtocol: http
+//	      resourceType: httpPath
+//	      resourceName: "/books/*"
+//	    operation: GET
+//	    decision: allow
+//
+// The version is the value of the version key of the rules file. Without it, rules files with a version 1 rule are version 1 files.
+// The rules are upgraded in the yaml node tree before they are decoded, so errors are reported at the lines and columns of the original file.
+
+// rulesVersion returns the version of the rules in the yaml node tree
+func rulesVersion(root *yamlv3.Node) (string, *RuleError) {
+	top := yamlTopNode(root)
+	if version := yamlMappingValue(top, "version"); version != nil {
+		if version.Kind != yamlv3.ScalarNode || (version.Value != "1" && version.Value != CurrentRulesVersion) {
+			err := fmt.Errorf("%w: %q (supported versions are 1 and %v)", ErrUnsupportedVersion, version.Value, CurrentRulesVersion)
+			return "", &RuleError{RuleIndex: -1, Path: "version", Line: version.Line, Column: version.Column, Err: err}
+		}
+		return version.Value, nil
+	}
+	rules := yamlMappingValue(top, "rules")
+	if rules == nil || rules.Kind != yamlv3.SequenceNode {
+		return CurrentRulesVersion, nil
+	}
+	for _, rule := range rules.Content {
+		if isVersion1Rule(rule) {
+			return "1", nil
+		}
+	}
+	return CurrentRulesVersion, nil
+}
+
+// isVersion1Rule returns true if the sender or the receiver of the rule is a string or if its resource has the protocol
+func isVersion1Rule(rule *yamlv3.Node) bool {
+	for _, key := range []string{"sender", "receiver"} {
+		if node := yamlMappingValue(rule, key); node != nil && node.Kind == yamlv3.ScalarNode && node.Tag != "!!null" {
+			return true
+		}
+	}
+	return yamlMappingValue(yamlMappingValue(rule, "resource"), "resourceProtocol") != nil
+}
+
+// upgradeRulesNode upgrades version 1 rules in the yaml node tree to the current version. It returns true if the rules were upgraded.
+func upgradeRulesNode(root *yamlv3.Node) (bool, *RuleError) {
+	version, ruleErr := rulesVersion(root)
+	if ruleErr != nil {
+		return false, ruleErr
+	}
+	if version != "1" {
+		return false, nil
+	}
+	rules := yamlMappingValue(yamlTopNode(root), "rules")
+	if rules != nil && rules.Kind == yamlv3.SequenceNode {
+		for _, rule := range rules.Content {
+			upgradeRuleNode(rule)
+		}
+	}
+	return true, nil
+}
+
+// upgradeRuleNode upgrades a version 1 rule in the yaml node tree: the sender and receiver strings become structures with the name and the type
+// and the protocol of the resource becomes the protocol of the rule
+func upgradeRuleNode(rule *yamlv3.Node) {
+	if rule == nil || rule.Kind != yamlv3.MappingNode {
+		return
+	}
+	upgradeSenderReceiverNode(yamlMappingValue(rule, "sender"), "senderName", "senderType")
+	upgradeSenderReceiverNode(yamlMappingValue(rule, "receiver"), "receiverName", "receiverType")
+
+	resource := yamlMappingValue(rule, "resource")
+	if resource == nil || resource.Kind != yamlv3.MappingNode || yamlMappingValue(rule, "protocol") != nil {
+		return // a rule with both protocols keeps the resourceProtocol key (it is reported as an unknown field)
+	}
+	for i := 0; i+1 < len(resource.Content); i += 2 {
+		if resource.Content[i].Value != "resourceProtocol" {
+			continue
+		}
+		key, value := *resource.Content[i], resource.Content[i+1]
+		key.Value = "protocol"
+		resource.Content = append(resource.Content[:i], resource.Content[i+2:]...)
+		rule.Content = append(rule.Content, &key, value)
+		return
+	}
+}
+
+// upgradeSenderReceiverNode replaces the name string of a version 1 sender or receiver by a structure with the name and the type
+func upgradeSenderReceiverNode(node *yamlv3.Node, nameKey, typeKey string) {
+	if node == nil || node.Kind != yamlv3.ScalarNode || node.Tag == "!!null" {
+		return
+	}
+	name := *node
+	scalar := func(value string) *yamlv3.Node {
+		return &yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: "!!str", Value: value, Line: name.Line, Column: name.Column}
+	}
+	*node = yamlv3.Node{Kind: yamlv3.MappingNode, Tag: "!!map", Line: name.Line, Column: name.Column, Content: []*yamlv3.Node{
+		scalar(nameKey), &name,
+		scalar(typeKey), scalar(version1Type(name.Value)),
+	}}
+}
+
+// version1Type returns the type of a version 1 sender or receiver name. version 1 had no types:
+// IPs and CIDRs were compared with the IP of the message and other names were compared with the service.
+func version1Type(name string) string {
+	if strings.TrimSpace(name) == "*" {
+		return "*"
+	}
+	list := strings.FieldsFunc(name, func(r rune) bool { return r == ';' || r == ',' })
+	if len(list) == 0 {
+		return "service"
+	}
+	for _, str := range list {
+		isIP, isCIDR, _, _ := isIpCIDR(strings.TrimSpace(str))
+		if !isIP && !isCIDR {
+			return "service"
+		}
+	}
+	return "subnet"
+}
+
+// upgradeRulesYaml returns the yaml data with version 1 rules upgraded to the current version (the data as is for rules of the current version).
+// It is used by the functions that decode the rules with yaml.v2. yaml errors are left to the decoding.
+func upgradeRulesYaml(data []byte) ([]byte, bool, error) {
+	var root yamlv3.Node
+	if yamlv3.Unmarshal(data, &root) != nil {
+		return data, false, nil
+	}
+	upgraded, ruleErr := upgradeRulesNode(&root)
+	if ruleErr != nil {
+		return nil, false, ruleErr
+	}
+	if !upgraded {
+		return data, false, nil
+	}
+	data, err := yamlv3.Marshal(&root)
+	return data, true, err
+}
+
+// RulesVersion returns the version of the rules in the yaml data: the version key or, without it, the version detected by the structure of the rules.
+// The version of each yaml document is detected, and data with a version 1 document is of version 1.
+func RulesVersion(data []byte) (string, error) {
+	documents, err := yamlDocuments(data)
+	if err != nil {
+		return "", &RuleError{RuleIndex: -1, Line: yamlErrorLine(err.Error()), Err: fmt.Errorf("%w: %v", ErrInvalidYaml, err)}
+	}
+	rulesFileVersion := CurrentRulesVersion
+	for _, root := range documents {
+		version, ruleErr := rulesVersion(root)
+		if ruleErr != nil {
+			return "", ruleErr
+		}
+		if version == "1" {
+			rulesFileVersion = version
+		}
+	}
+	return rulesFileVersion, nil
+}
+
+// MigrateRules reads rules of any supported version (see ParseRules) and writes them in the yaml format of the current version (with the version key).
+// Each yaml document is migrated (with its own version) and written as a document. Comments of the original file are not kept.
+func MigrateRules(data []byte) ([]byte, error) {
+	documents, err := yamlDocuments(data)
+	if err != nil {
+		return nil, &RuleError{RuleIndex: -1, Line: yamlErrorLine(err.Error()), Err: fmt.Errorf("%w: %v", ErrInvalidYaml, err)}
+	}
+	if len(documents) == 0 {
+		documents = []*yamlv3.Node{{}} // empty rules
+	}
+	migrated := []byte{}
+	for i, root := range documents {
+		rules, err := parseRulesNode(root)
+		if err != nil {
+			return nil, err
+		}
+		rules.Version = CurrentRulesVersion
+		document, err := RulesToYaml(rules)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			migrated = append(migrated, "---\n"...)
+		}
+		migrated = append(migrated, document...)
+	}
+	return migrated, nil
+}
+
+// MigrateRulesFile function reads rules from a yaml file and writes them in the current version. See MigrateRules.
+func MigrateRulesFile(filename string) ([]byte, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return MigrateRules(data)
+}
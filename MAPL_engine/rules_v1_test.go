@@ -0,0 +1,161 @@
+package MAPL_engine
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestRulesVersion1 reads the version 1 example and checks that the upgraded rules are the rules of the version 2 example
+func TestRulesVersion1(t *testing.T) {
+	rules, err := ParseRulesFromFile("../examples/rules_resources_v1.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rules.Version != CurrentRulesVersion {
+		t.Errorf("expected version %v, got %q", CurrentRulesVersion, rules.Version)
+	}
+	messages, err := ParseMessagesFromFile("../examples/messages_resources.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report := CheckExpectations(messages, rules); report.Failed > 0 {
+		t.Error(report.String())
+	}
+
+	rule := rules.Rules[0]
+	if rule.Protocol != "http" || rule.Sender.SenderName != "A.my_namespace" || rule.Sender.SenderType != "service" || rule.Receiver.ReceiverType != "service" {
+		t.Errorf("unexpected upgraded rule: %+v", rule)
+	}
+
+	migrated, err := MigrateRulesFile("../examples/rules_resources_v1.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version, _ := RulesVersion(migrated); version != CurrentRulesVersion || !strings.HasPrefix(string(migrated), "version: \"2\"\n") {
+		t.Errorf("unexpected migrated rules:\n%s", migrated)
+	}
+	fromMigrated, err := ParseRules(migrated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range rules.Rules {
+		if RuleMD5Hash(rules.Rules[i]) != RuleMD5Hash(fromMigrated.Rules[i]) {
+			t.Errorf("the hash of rule #%v changed in the migration", i)
+		}
+	}
+
+	legacy := YamlReadRulesFromFile("../examples/rules_resources_v1.yaml")
+	if legacy.Version != CurrentRulesVersion || RuleMD5Hash(legacy.Rules[1]) != RuleMD5Hash(rules.Rules[1]) {
+		t.Errorf("unexpected rules of YamlReadRulesFromFile: %+v", legacy)
+	}
+	one := YamlReadOneRule("rule_id: 0\nsender: 10.0.0.0/8\nreceiver: B\nresource:\n  resourceProtocol: tcp\n  resourceType: port\n  resourceName: 80\n")
+	if one.Sender.SenderType != "subnet" || one.Receiver.ReceiverType != "service" || one.Protocol != "tcp" {
+		t.Errorf("unexpected rule of YamlReadOneRule: %+v", one)
+	}
+}
+
+func TestRulesVersionDetection(t *testing.T) {
+	tests := []struct {
+		data    string
+		version string
+	}{
+		{strictRules, "2"},
+		{"version: 2\n" + strictRules, "2"},
+		{"version: \"1\"\nrules: []\n", "1"},
+		{"rules:\n  - rule_id: 0\n    sender: A\n", "1"},
+		{"rules:\n  - rule_id: 0\n    receiver: \"*\"\n", "1"},
+		{"rules:\n  - rule_id: 0\n    resource:\n      resourceProtocol: tcp\n", "1"},
+		{"rules:\n  - rule_id: 0\n    sender:\n", "2"},
+		{"", "2"},
+	}
+	for _, test := range tests {
+		version, err := RulesVersion([]byte(test.data))
+		if err != nil || version != test.version {
+			t.Errorf("%q: expected version %v, got %v (%v)", test.data, test.version, version, err)
+		}
+	}
+
+	_, err := ParseRules([]byte("version: 3\n" + strictRules))
+	ruleErr, ok := err.(*RuleError)
+	if !ok || !errors.Is(err, ErrUnsupportedVersion) || ruleErr.Path != "version" || ruleErr.Line != 1 || ruleErr.Column != 10 {
+		t.Errorf("expected an unsupported version error, got %v", err)
+	}
+}
+
+func TestRulesVersion1Errors(t *testing.T) {
+	data := `rules:
+  - rule_id: 0
+    sender: "10.0.0.0/8;A"
+    receiver: "10.0.0.1;10.1.0.0/16"
+    resource:
+      resourceProtocol: tcp
+      resourceType: port
+      resourceName: 80
+    operation: "*"
+    decision: allow
+`
+	rules, err := ParseRules([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rules.Rules[0].Sender.SenderType != "service" || rules.Rules[0].Receiver.ReceiverType != "subnet" {
+		t.Errorf("unexpected types: %+v", rules.Rules[0])
+	}
+
+	// the errors are at the lines and columns of the version 1 rules
+	tests := []struct {
+		old, new string
+		err      error
+		path     string
+		line     int
+		column   int
+	}{
+		{"      resourceType: port", "      resource_type: port", ErrUnknownField, "rules[0].resource.resource_type", 7, 7},
+		{"    operation", "    protocol: tcp\n    operation", ErrUnknownField, "rules[0].resource.resourceProtocol", 6, 7},
+		{"      resourceProtocol: tcp", "      resourceProtocol: \"(\"", ErrInvalidPattern, "rules[0].protocol", 6, 25},
+	}
+	for _, test := range tests {
+		_, err := ParseRules([]byte(strings.Replace(data, test.old, test.new, 1)))
+		ruleErr, ok := err.(*RuleError)
+		if !ok {
+			t.Fatalf("%v: expected a *RuleError, got %v", test.path, err)
+		}
+		if !errors.Is(err, test.err) || ruleErr.Path != test.path || ruleErr.Line != test.line || ruleErr.Column != test.column {
+			t.Errorf("%v: unexpected error: %v", test.path, err)
+		}
+	}
+}
+
+// TestMigrateRulesDocuments migrates a rules file with a version 1 document and a version 2 document
+func TestMigrateRulesDocuments(t *testing.T) {
+	version1 := "rules:\n  - rule_id: 0\n    sender: A\n    receiver: B\n    resource:\n      resourceProtocol: tcp\n      resourceType: port\n      resourceName: 80\n    operation: \"*\"\n    decision: allow\n"
+	version2 := strings.Replace(strictRules, "decision: allow", "decision: block", 1)
+	for data, expected := range map[string]string{version2 + "---\n" + version1: "blockallow", version1 + "---\n" + version2: "allowblock"} {
+		if version, err := RulesVersion([]byte(data)); err != nil || version != "1" {
+			t.Errorf("expected version 1, got %v (%v)", version, err)
+		}
+		migrated, err := MigrateRules([]byte(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		documents := strings.Split(string(migrated), "---\n")
+		if len(documents) != 2 {
+			t.Fatalf("expected 2 documents:\n%s", migrated)
+		}
+		decisions := ""
+		for _, document := range documents {
+			rules, err := ParseRules([]byte(document))
+			if err != nil || rules.Version != CurrentRulesVersion || len(rules.Rules) != 1 {
+				t.Fatalf("unexpected migrated document (%v):\n%s", err, document)
+			}
+			decisions += rules.Rules[0].Decision
+		}
+		if decisions != expected {
+			t.Errorf("unexpected decisions of the migrated rules: %v", decisions)
+		}
+		if version, _ := RulesVersion(migrated); version != CurrentRulesVersion {
+			t.Errorf("the migrated rules should be of version %v", CurrentRulesVersion)
+		}
+	}
+}
@@ -0,0 +1,347 @@
+package MAPL_engine
+
+//go:generate go run ../cmd/mapl schema -o ../docs/rules.schema.json rules
+//go:generate go run ../cmd/mapl schema -o ../docs/messages.schema.json messages
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JSON Schema (draft-07) of the rules and messages files. The schema is generated from the yaml fields of the structures in definitions.go
+// and adds the values that the engine supports (decisions, sender and receiver types, protocols, resource types, condition keywords and methods)
+// and the requirements that depend on other fields (for example a sender of type "subnet" must be a list of IPs or CIDRs).
+// Editors use the schema for autocompletion and to mark mistakes, for example with the yaml language server:
+//
+//	# yaml-language-server: $schema=https://raw.githubusercontent.com/octarinesec/MAPL/master/docs/rules.schema.json
+
+type jsonSchema map[string]interface{}
+
+const jsonSchemaDraft = "http://json-schema.org/draft-07/schema#"
+
+var (
+	schemaDecisions     = []string{"allow", "alert", "block"}
+	schemaModes         = []string{RuleModeEnforce, RuleModeAudit}
+	schemaProtocols     = []string{"http", "tcp", "kafka", "grpc"}
+	schemaResourceTypes = []string{"httpPath", "port", "kafkaTopic", "consumerGroup", "grpcService", "grpcMethod"}
+	schemaOperations    = []string{"GET", "POST", "PUT", "DELETE", "PATCH", "HEAD", "OPTIONS", "TRACE", "PRODUCE", "CONSUME", "read", "write"}
+
+	// the resource types of each protocol (see the MAPL specification)
+	schemaProtocolResourceTypes = map[string][]string{
+		"http":  {"httpPath"},
+		"tcp":   {"port"},
+		"kafka": {"kafkaTopic", "consumerGroup"},
+		"grpc":  {"grpcService", "grpcMethod"},
+	}
+)
+
+// schemaCustomizers add the supported values and requirements to the schema of the structures (by the name of the structure)
+var schemaCustomizers = map[string]func(schema jsonSchema){
+	"Rules":             customizeRulesSchema,
+	"Rule":              customizeRuleSchema,
+	"Sender":            func(schema jsonSchema) { customizeEndpointSchema(schema, "senderName", "senderType") },
+	"Receiver":          func(schema jsonSchema) { customizeEndpointSchema(schema, "receiverName", "receiverType") },
+	"Resource":          customizeResourceSchema,
+	"ANDConditions":     customizeANDConditionsSchema,
+	"Condition":         customizeConditionSchema,
+	"Messages":          customizeMessagesSchema,
+	"MessageAttributes": customizeMessageSchema,
+}
+
+// RulesJSONSchema returns the JSON Schema of the rules files.
+// The condition keywords are the keywords in the attribute registry (including keywords registered with RegisterAttribute).
+func RulesJSONSchema() ([]byte, error) {
+	return newJSONSchema(reflect.TypeOf(Rules{}), "MAPL rules", "rules files of the MAPL engine (https://github.com/octarinesec/MAPL)")
+}
+
+// MessagesJSONSchema returns the JSON Schema of the messages files
+func MessagesJSONSchema() ([]byte, error) {
+	return newJSONSchema(reflect.TypeOf(Messages{}), "MAPL messages", "messages files of the MAPL engine (https://github.com/octarinesec/MAPL)")
+}
+
+func newJSONSchema(t reflect.Type, title, description string) ([]byte, error) {
+	definitions := jsonSchema{}
+	schemaOfType(t, definitions)
+
+	// the top level structure is the schema itself
+	schema := definitions[t.Name()].(jsonSchema)
+	delete(definitions, t.Name())
+	schema["$schema"] = jsonSchemaDraft
+	schema["title"] = title
+	schema["description"] = description
+	schema["definitions"] = definitions
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+// schemaOfType returns the schema of a type. Structures are added to the definitions and referenced.
+func schemaOfType(t reflect.Type, definitions jsonSchema) jsonSchema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == reflect.TypeOf(time.Duration(0)) { // an integer (nanoseconds) or a duration string (example: 1.5s)
+		return jsonSchema{"type": []string{"integer", "string"}}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		name := t.Name()
+		if _, ok := definitions[name]; !ok {
+			definitions[name] = jsonSchema{} // added before the fields (for recursive structures)
+			properties := jsonSchema{}
+			fields := map[string]reflect.Type{}
+			yamlStructFields(t, fields)
+			for key, fieldType := range fields {
+				properties[key] = schemaOfType(fieldType, definitions)
+			}
+			schema := jsonSchema{"type": "object", "properties": properties, "additionalProperties": false}
+			if customize, ok := schemaCustomizers[name]; ok {
+				customize(schema)
+			}
+			definitions[name] = schema
+		}
+		return jsonSchema{"$ref": "#/definitions/" + name}
+	case reflect.Slice, reflect.Array:
+		return jsonSchema{"type": "array", "items": schemaOfType(t.Elem(), definitions)}
+	case reflect.Map:
+		return jsonSchema{"type": "object", "additionalProperties": schemaOfType(t.Elem(), definitions)}
+	case reflect.String: // yaml scalars (for example rule_id: 0 or value: 10) are read into strings
+		return jsonSchema{"type": []string{"string", "number", "boolean"}}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return jsonSchema{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return jsonSchema{"type": "number"}
+	case reflect.Bool:
+		return jsonSchema{"type": "boolean"}
+	}
+	return jsonSchema{}
+}
+
+func properties(schema jsonSchema) jsonSchema {
+	return schema["properties"].(jsonSchema)
+}
+
+// setProperty replaces the schema of a property (and adds the description)
+func setProperty(schema jsonSchema, key string, property jsonSchema, description string) {
+	if description != "" {
+		property["description"] = description
+	}
+	properties(schema)[key] = property
+}
+
+// withCases returns the values in lower case, upper case and capitalized (as accepted by the engine)
+func withCases(values []string, capitalized bool) []string {
+	list := []string{}
+	for _, v := range values {
+		list = appendUnique(list, strings.ToLower(v))
+		list = appendUnique(list, strings.ToUpper(v))
+		if capitalized {
+			list = appendUnique(list, strings.ToUpper(v[:1])+strings.ToLower(v[1:]))
+		}
+	}
+	return list
+}
+
+func appendUnique(list []string, str string) []string {
+	for _, s := range list {
+		if s == str {
+			return list
+		}
+	}
+	return append(list, str)
+}
+
+// caseInsensitivePattern returns a pattern of the exact string regardless of case (the patterns of JSON Schema do not have flags)
+func caseInsensitivePattern(str string) string {
+	pattern := "^"
+	for _, r := range str {
+		lower, upper := strings.ToLower(string(r)), strings.ToUpper(string(r))
+		if lower == upper {
+			pattern += string(r)
+		} else {
+			pattern += "[" + lower + upper + "]"
+		}
+	}
+	return pattern + "$"
+}
+
+// listOrPattern is a value in the list or a list of values (separated by ';') or a pattern with wildcards
+func listOrPattern(values []string) jsonSchema {
+	return jsonSchema{"anyOf": []jsonSchema{
+		{"type": "string", "enum": values},
+		{"type": "string", "pattern": "[;*?]"},
+	}}
+}
+
+// an IPv4 or IPv6 address or CIDR
+const ipOrCIDRPattern = `([0-9]{1,3}(\.[0-9]{1,3}){3}|[0-9A-Fa-f]*:[0-9A-Fa-f:.]*)(/[0-9]{1,3})?`
+
+const ipOrCIDRListPattern = `^\s*` + ipOrCIDRPattern + `(\s*[;,]\s*` + ipOrCIDRPattern + `)*\s*$`
+
+//-------------------------------------------------------------------------------------------
+// rules
+
+func customizeRulesSchema(schema jsonSchema) {
+	schema["required"] = []string{"rules"}
+	version, _ := strconv.Atoi(CurrentRulesVersion) // the version may be written as a number or as a string
+	setProperty(schema, "version", jsonSchema{"enum": []interface{}{CurrentRulesVersion, version}},
+		"the version of the rules format (version 1 files are upgraded by the engine and by 'mapl migrate' but are not described by the schema)")
+}
+
+// schemaTimePattern is the pattern of the timestamps and dates of the validity period of the rules
+const schemaTimePattern = `^\d{4}-\d{2}-\d{2}([T ]|$)`
+
+func customizeRuleSchema(schema jsonSchema) {
+	schema["required"] = []string{"sender", "receiver", "protocol", "operation", "decision"}
+	protocol := listOrPattern(withCases(schemaProtocols, false))
+	for _, p := range schemaProtocols { // the protocol is compared regardless of case
+		protocol["anyOf"] = append(protocol["anyOf"].([]jsonSchema), jsonSchema{"type": "string", "pattern": caseInsensitivePattern(p)})
+	}
+	setProperty(schema, "protocol", protocol,
+		"the protocol (regardless of case), a list separated by ';' or a pattern with wildcards ('*', '?')")
+	setProperty(schema, "operation", jsonSchema{"anyOf": []jsonSchema{
+		{"type": "string", "enum": append(withCases(schemaOperations, false), "*")},
+		{"type": "string"}, // for example the method of GRPC
+	}}, "the operation (read, write, an HTTP or KAFKA verb, a GRPC method, a list separated by ';' or a pattern with wildcards)")
+	setProperty(schema, "decision", jsonSchema{"type": "string", "enum": withCases(schemaDecisions, true)}, "")
+	setProperty(schema, "mode", jsonSchema{"type": "string", "enum": withCases(schemaModes, false)},
+		"enforce (the default) or audit: the decisions of audit rules are reported but do not change the decision of the message")
+	setProperty(schema, "description", jsonSchema{"type": "string"}, "what the rule is for (not used in the decisions)")
+	setProperty(schema, "owner", jsonSchema{"type": "string"}, "the owner of the rule (not used in the decisions)")
+	setProperty(schema, "tags", jsonSchema{"type": "array", "items": jsonSchema{"type": "string"}}, "tags of the rule (not used in the decisions)")
+	setProperty(schema, "validFrom", jsonSchema{"type": "string", "pattern": schemaTimePattern},
+		"the rule applies to messages from this timestamp (RFC3339) or date with an optional time zone (example: 2026-11-01 Europe/Berlin)")
+	setProperty(schema, "validUntil", jsonSchema{"type": "string", "pattern": schemaTimePattern},
+		"the rule applies to messages until this timestamp (RFC3339) or date with an optional time zone (the date is included)")
+
+	// the resource type must match the protocol
+	protocols := []string{}
+	for protocol := range schemaProtocolResourceTypes {
+		protocols = append(protocols, protocol)
+	}
+	sort.Strings(protocols)
+	conditions := []jsonSchema{}
+	for _, protocol := range protocols {
+		conditions = append(conditions, jsonSchema{
+			"if": jsonSchema{
+				"properties": jsonSchema{"protocol": jsonSchema{"type": "string", "pattern": caseInsensitivePattern(protocol)}},
+				"required":   []string{"protocol"},
+			},
+			"then": jsonSchema{
+				"properties": jsonSchema{"resource": jsonSchema{
+					"properties": jsonSchema{"resourceType": jsonSchema{"anyOf": []jsonSchema{
+						{"type": "string", "enum": append(schemaProtocolResourceTypes[protocol], "*")},
+						{"type": "string", "pattern": "[;?]|.[*]|[*]."},
+					}}},
+				}},
+			},
+		})
+	}
+	schema["allOf"] = conditions
+}
+
+func customizeEndpointSchema(schema jsonSchema, nameKey, typeKey string) {
+	schema["required"] = []string{nameKey, typeKey}
+	setProperty(schema, typeKey, jsonSchema{"type": "string", "enum": senderReceiverTypes}, "") // the types are compared exactly (lower case)
+	schema["allOf"] = []jsonSchema{{
+		"if": jsonSchema{
+			"properties": jsonSchema{typeKey: jsonSchema{"enum": []string{"subnet"}}},
+			"required":   []string{typeKey},
+		},
+		"then": jsonSchema{
+			"properties": jsonSchema{nameKey: jsonSchema{"type": "string", "pattern": ipOrCIDRListPattern}},
+		},
+	}}
+}
+
+func customizeResourceSchema(schema jsonSchema) {
+	setProperty(schema, "resourceType", listOrPattern(append(append([]string{}, schemaResourceTypes...), "*")),
+		"the resource type of the protocol, a list separated by ';' or a pattern with wildcards ('*', '?')")
+}
+
+func customizeANDConditionsSchema(schema jsonSchema) {
+	schema["required"] = []string{"ANDconditions"}
+}
+
+func customizeConditionSchema(schema jsonSchema) {
+	schema["required"] = []string{"attribute"}
+
+	// the methods of each condition keyword
+	keywords := []string{"true", "TRUE", "false", "FALSE"}
+	methodsOfKeywords := map[string][]string{}
+	addMethods := func(keyword string, methods []string) {
+		keywords = append(keywords, keyword)
+		key := strings.Join(methods, ",")
+		methodsOfKeywords[key] = append(methodsOfKeywords[key], keyword)
+	}
+	for _, attribute := range Attributes() {
+		addMethods(attribute.Keyword, attribute.Methods())
+	}
+	addMethods("senderLabels", selectorMethods)
+	addMethods("receiverLabels", selectorMethods)
+
+	setProperty(schema, "attribute", jsonSchema{"anyOf": []jsonSchema{
+		{"type": "string", "enum": keywords},
+		{"type": "string", "pattern": `^(senderLabel|receiverLabel)\[[^\]]+\]$`},
+		{"type": "string", "pattern": "^" + customAttributePrefix + ".+"},
+	}}, "the condition keyword (see docs/SUPPORTED_ATTRIBUTES.md), senderLabel[key], receiverLabel[key] or custom:<name>")
+	setProperty(schema, "method", jsonSchema{"type": "string"}, "the method (in upper case or in lower case). The methods depend on the condition keyword")
+
+	methodLists := []string{}
+	for methods := range methodsOfKeywords {
+		methodLists = append(methodLists, methods)
+	}
+	sort.Strings(methodLists)
+	conditions := []jsonSchema{}
+	for _, methods := range methodLists {
+		conditions = append(conditions, methodsCondition(jsonSchema{"type": "string", "enum": methodsOfKeywords[methods]}, strings.Split(methods, ",")))
+	}
+	conditions = append(conditions, methodsCondition(jsonSchema{"type": "string", "pattern": `^(senderLabel|receiverLabel)\[`}, stringMethods))
+	schema["allOf"] = conditions
+}
+
+// methodsCondition requires one of the methods for the condition keywords that match the attribute schema
+func methodsCondition(attribute jsonSchema, methods []string) jsonSchema {
+	return jsonSchema{
+		"if": jsonSchema{
+			"properties": jsonSchema{"attribute": attribute},
+			"required":   []string{"attribute"},
+		},
+		"then": jsonSchema{
+			"properties": jsonSchema{"method": jsonSchema{"enum": withCases(methods, false)}},
+			"required":   []string{"method"},
+		},
+	}
+}
+
+//-------------------------------------------------------------------------------------------
+// messages
+
+func customizeMessagesSchema(schema jsonSchema) {
+	schema["required"] = []string{"messages"}
+}
+
+func customizeMessageSchema(schema jsonSchema) {
+	setProperty(schema, "request_protocol", jsonSchema{"anyOf": []jsonSchema{
+		{"type": "string", "enum": withCases(schemaProtocols, false)},
+		{"type": "string"},
+	}}, "the protocol of the message (the resource type is derived from it)")
+	setProperty(schema, "request_type", jsonSchema{"type": "string", "enum": []string{"kafkaTopic", "consumerGroup"}},
+		"the resource type of a KAFKA message (the resource type of other protocols is derived from the protocol)")
+	setProperty(schema, "request_time", jsonSchema{"type": "string", "format": "date-time"}, "RFC3339 timestamp")
+	for _, key := range []string{"sender_ip", "receiver_ip"} {
+		setProperty(schema, key, jsonSchema{"type": "string", "anyOf": []jsonSchema{{"format": "ipv4"}, {"format": "ipv6"}}}, "")
+	}
+	for _, key := range []string{"sender_labels", "receiver_labels"} {
+		setProperty(schema, key, jsonSchema{"type": "string"}, `the labels as a json map (example: '{"app":"web"}')`)
+	}
+	names := []string{}
+	for _, name := range decisionNames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	setProperty(schema, "expected_decision", jsonSchema{"type": "string", "enum": withCases(names, false)}, "the expected decision (see CheckExpectations)")
+}
@@ -0,0 +1,279 @@
+package MAPL_engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// validate is a minimal JSON Schema validator of the keywords used in the generated schemas. It returns the first error (nil if the value is valid).
+func validate(root, schema map[string]interface{}, value interface{}, path string) error {
+	if ref, ok := schema["$ref"].(string); ok {
+		name := strings.TrimPrefix(ref, "#/definitions/")
+		return validate(root, root["definitions"].(map[string]interface{})[name].(map[string]interface{}), value, path)
+	}
+	if types, ok := schema["type"]; ok && !hasType(types, value) {
+		return fmt.Errorf("%v: %v is not of type %v", path, value, types)
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, v := range enum {
+			found = found || fmt.Sprint(v) == fmt.Sprint(value)
+		}
+		if !found {
+			return fmt.Errorf("%v: %v is not one of %v", path, value, enum)
+		}
+	}
+	if pattern, ok := schema["pattern"].(string); ok {
+		if str, isString := value.(string); isString && !regexp.MustCompile(pattern).MatchString(str) {
+			return fmt.Errorf("%v: %q does not match %v", path, str, pattern)
+		}
+	}
+	if object, ok := value.(map[string]interface{}); ok {
+		for _, key := range asList(schema["required"]) {
+			if _, exists := object[key.(string)]; !exists {
+				return fmt.Errorf("%v: %v is required", path, key)
+			}
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		for key, v := range object {
+			if property, ok := properties[key]; ok {
+				if err := validate(root, property.(map[string]interface{}), v, path+"."+key); err != nil {
+					return err
+				}
+				continue
+			}
+			switch additional := schema["additionalProperties"].(type) {
+			case bool:
+				if !additional {
+					return fmt.Errorf("%v: unknown property %v", path, key)
+				}
+			case map[string]interface{}:
+				if err := validate(root, additional, v, path+"."+key); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if items, ok := schema["items"].(map[string]interface{}); ok {
+		for i, v := range asList(value) {
+			if err := validate(root, items, v, fmt.Sprintf("%v[%v]", path, i)); err != nil {
+				return err
+			}
+		}
+	}
+	if anyOf, ok := schema["anyOf"].([]interface{}); ok {
+		var err error
+		for _, s := range anyOf {
+			if err = validate(root, s.(map[string]interface{}), value, path); err == nil {
+				break
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for _, s := range asList(schema["allOf"]) {
+		if err := validate(root, s.(map[string]interface{}), value, path); err != nil {
+			return err
+		}
+	}
+	if condition, ok := schema["if"].(map[string]interface{}); ok && validate(root, condition, value, path) == nil {
+		if then, ok := schema["then"].(map[string]interface{}); ok {
+			return validate(root, then, value, path)
+		}
+	}
+	return nil
+}
+
+func asList(v interface{}) []interface{} {
+	list, _ := v.([]interface{})
+	return list
+}
+
+func hasType(types interface{}, value interface{}) bool {
+	for _, t := range append(asList(types), types) {
+		switch t {
+		case "string":
+			switch value.(type) {
+			case string, time.Time: // yaml timestamps are strings in json
+				return true
+			}
+		case "number", "integer":
+			switch value.(type) {
+			case int, int64, float64:
+				return true
+			}
+		case "boolean":
+			if _, ok := value.(bool); ok {
+				return true
+			}
+		case "object":
+			if _, ok := value.(map[string]interface{}); ok {
+				return true
+			}
+		case "array":
+			if _, ok := value.([]interface{}); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func readSchema(t *testing.T, schemaFunc func() ([]byte, error)) map[string]interface{} {
+	data, err := schemaFunc()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatal(err)
+	}
+	return schema
+}
+
+func validateYaml(t *testing.T, schema map[string]interface{}, data []byte) error {
+	var value interface{}
+	if err := yamlv3.Unmarshal(data, &value); err != nil {
+		t.Fatal(err)
+	}
+	return validate(schema, schema, value, "")
+}
+
+// TestSchemaExamples validates the rules and messages files of the examples with the schemas
+func TestSchemaExamples(t *testing.T) {
+	rulesSchema := readSchema(t, RulesJSONSchema)
+	messagesSchema := readSchema(t, MessagesJSONSchema)
+	for _, pair := range examplePairs {
+		for i, filename := range pair {
+			data, err := ioutil.ReadFile(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			schema := rulesSchema
+			if i == 1 {
+				schema = messagesSchema
+			}
+			if err := validateYaml(t, schema, data); err != nil {
+				t.Errorf("%v: %v", filename, err)
+			}
+		}
+	}
+}
+
+func TestSchemaErrors(t *testing.T) {
+	rulesSchema := readSchema(t, RulesJSONSchema)
+	rule := `rules:
+  - rule_id: 0
+    sender:
+      senderName: "10.0.0.0/8;10.1.1.1"
+      senderType: subnet
+    receiver:
+      receiverName: "B"
+      receiverType: service
+    protocol: HTTP
+    resource:
+      resourceType: httpPath
+      resourceName: "/books/*"
+    operation: GET
+    DNFconditions:
+      - ANDconditions:
+        - attribute: payloadSize
+          method: GT
+          value: 1024
+    decision: allow
+`
+	if err := validateYaml(t, rulesSchema, []byte(rule)); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct{ old, new string }{
+		{"    receiver:", "    reciever:"},
+		{"decision: allow", "decision: allowed"},
+		{`senderName: "10.0.0.0/8;10.1.1.1"`, `senderName: "A"`},
+		{"senderType: subnet", "senderType: pod"},
+		{"resourceType: httpPath", "resourceType: port"},
+		{"protocol: HTTP", "protocol: htpp"},
+		{"method: GT", "method: RE"},
+		{"attribute: payloadSize", "attribute: payload_size"},
+		{"      - ANDconditions:", "      - ANDcondition:"},
+	}
+	for _, test := range tests {
+		if err := validateYaml(t, rulesSchema, []byte(strings.Replace(rule, test.old, test.new, 1))); err == nil {
+			t.Errorf("%v: expected an error", test.new)
+		}
+	}
+
+	messagesSchema := readSchema(t, MessagesJSONSchema)
+	for _, data := range []string{
+		"messages:\n  - message_id: 0\n    sender_nam: A\n",
+		"messages:\n  - message_id: 0\n    expected_decision: allowed\n",
+	} {
+		if err := validateYaml(t, messagesSchema, []byte(data)); err == nil {
+			t.Errorf("%v: expected an error", data)
+		}
+	}
+}
+
+// TestSchemaFiles tests that the schema files in the docs folder are up to date (they are written by go generate)
+func TestSchemaFiles(t *testing.T) {
+	for filename, schemaFunc := range map[string]func() ([]byte, error){
+		"../docs/rules.schema.json":    RulesJSONSchema,
+		"../docs/messages.schema.json": MessagesJSONSchema,
+	} {
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		schema, _ := schemaFunc()
+		if !bytes.Equal(bytes.TrimSpace(data), schema) {
+			t.Errorf("%v is not up to date (run go generate in MAPL_engine)", filename)
+		}
+	}
+}
+
+// TestSchemaEnums reads a rule with each of the values of the enums of the rules schema (the values that the schema accepts must be accepted by ParseRules)
+func TestSchemaEnums(t *testing.T) {
+	definitions := readSchema(t, RulesJSONSchema)["definitions"].(map[string]interface{})
+	enum := func(definition, property string) []interface{} {
+		properties := definitions[definition].(map[string]interface{})["properties"].(map[string]interface{})
+		values := properties[property].(map[string]interface{})["enum"]
+		if values == nil {
+			t.Fatalf("%v.%v has no enum", definition, property)
+		}
+		return values.([]interface{})
+	}
+	tests := []struct {
+		definition, property string
+		old                  string
+	}{
+		{"Sender", "senderType", "senderType: service"},
+		{"Receiver", "receiverType", "receiverType: service"},
+		{"Rule", "decision", "decision: allow"},
+		{"Rule", "mode", "decision: allow"},
+	}
+	for _, test := range tests {
+		for _, value := range enum(test.definition, test.property) {
+			rule := strictRules
+			switch {
+			case test.property == "mode":
+				rule = strings.Replace(rule, test.old, test.old+"\n    mode: "+value.(string), 1)
+			case value == "subnet":
+				rule = strings.Replace(strings.Replace(rule, `"A.my_namespace"`, "10.0.0.0/8", 1), `"B.my_namespace"`, "10.0.0.1", 1)
+				fallthrough
+			default:
+				rule = strings.Replace(rule, test.old, test.property+": \""+value.(string)+"\"", 1)
+			}
+			if _, err := ParseRules([]byte(rule)); err != nil {
+				t.Errorf("%v.%v %q: %v", test.definition, test.property, value, err)
+			}
+		}
+	}
+}
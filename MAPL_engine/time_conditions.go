@@ -0,0 +1,402 @@
+package MAPL_engine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// methods of time attributes:
+// BEFORE, AFTER: the time is before (or after) a timestamp or a date. examples: "2026-12-31", "2026-12-31 Europe/Berlin", "2026-12-31T18:00:00Z"
+// EQ, NEQ, NE: the time is (or is not) the timestamp or within the date
+// BETWEEN, NBETWEEN: the time is (or is not) between two timestamps or dates (inclusive). example: "2026-01-01;2026-03-31"
+// IN_WINDOW, NOT_IN_WINDOW: the time is (or is not) in a recurring window. example: "Mon-Fri 01:00-04:00 Europe/Berlin"
+// CRON, NCRON: the time matches (or does not match) a cron expression. example: "* 1-3 * * Mon-Fri Europe/Berlin"
+var timeMethods = []string{"EQ", "NEQ", "NE", "BEFORE", "AFTER", "BETWEEN", "NBETWEEN", "IN_WINDOW", "NOT_IN_WINDOW", "CRON", "NCRON", "EX", "NEX"}
+
+const dateLayout = "2006-01-02"
+
+var dayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// timeSchedule is a recurring window (IN_WINDOW) or a cron expression (CRON)
+type timeSchedule interface {
+	contains(t time.Time) bool
+}
+
+// convertTimeValue converts the condition's value according to the time method
+func convertTimeValue(c *Condition) error {
+	var err error
+	switch strings.ToUpper(c.Method) {
+	case "IN_WINDOW", "NOT_IN_WINDOW":
+		c.schedule, err = parseTimeWindow(c.Value)
+	case "CRON", "NCRON":
+		c.schedule, err = parseCronSchedule(c.Value)
+	case "BETWEEN", "NBETWEEN":
+		c.ValueTime, c.ValueTimeEnd, err = parseTimeRange(c.Value)
+	default:
+		c.ValueTime, c.ValueTimeEnd, err = parseTimeValue(c.Value)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidValue, err)
+	}
+	return nil
+}
+
+// compareTimeFunc compares one time value according the method string
+func compareTimeFunc(value time.Time, c *Condition) bool {
+	switch c.Method {
+	case "EQ", "eq", "BETWEEN", "between":
+		return !value.Before(c.ValueTime) && value.Before(c.ValueTimeEnd)
+	case "NEQ", "neq", "NE", "ne", "NBETWEEN", "nbetween":
+		return value.Before(c.ValueTime) || !value.Before(c.ValueTimeEnd)
+	case "BEFORE", "before":
+		return value.Before(c.ValueTime)
+	case "AFTER", "after":
+		return !value.Before(c.ValueTimeEnd)
+	case "IN_WINDOW", "in_window", "CRON", "cron":
+		return c.schedule != nil && c.schedule.contains(value)
+	case "NOT_IN_WINDOW", "not_in_window", "NCRON", "ncron":
+		return c.schedule != nil && !c.schedule.contains(value)
+	}
+	return false
+}
+
+// parseTimeValue parses a timestamp (RFC3339) or a date with an optional time zone (UTC by default).
+// It returns the range [from,to) of the value: a timestamp is a range of one nanosecond and a date is a range of one day.
+func parseTimeValue(value string) (from, to time.Time, err error) {
+	value = strings.TrimSpace(value)
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, t.Add(time.Nanosecond), nil
+	}
+
+	fields := strings.Fields(value)
+	if len(fields) == 0 || len(fields) > 2 {
+		return from, to, fmt.Errorf("%q is not a timestamp or a date (examples: 2026-12-31T18:00:00Z, 2026-12-31 Europe/Berlin)", value)
+	}
+	location := time.UTC
+	if len(fields) == 2 {
+		location, err = loadTimeZone(fields[1])
+		if err != nil {
+			return from, to, err
+		}
+	}
+	from, err = time.ParseInLocation(dateLayout, fields[0], location)
+	if err != nil {
+		return from, to, fmt.Errorf("%q is not a timestamp or a date (examples: 2026-12-31T18:00:00Z, 2026-12-31 Europe/Berlin)", value)
+	}
+	return from, from.AddDate(0, 0, 1), nil
+}
+
+// parseTimeRange parses two timestamps or dates ("low;high"). It returns the range [from,to) from the start of low to the end of high.
+func parseTimeRange(value string) (from, to time.Time, err error) {
+	bounds := splitValueList(value)
+	if len(bounds) != 2 {
+		return from, to, fmt.Errorf("%q is not a range of two timestamps or dates (example: 2026-01-01;2026-03-31)", value)
+	}
+	from, _, err = parseTimeValue(bounds[0])
+	if err != nil {
+		return from, to, err
+	}
+	_, to, err = parseTimeValue(bounds[1])
+	if err != nil {
+		return from, to, err
+	}
+	if !from.Before(to) {
+		return from, to, fmt.Errorf("%q is an empty range", value)
+	}
+	return from, to, nil
+}
+
+func loadTimeZone(name string) (*time.Location, error) {
+	if name == "" || name == "Local" { // the local time zone of the engine is not used
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	return location, nil
+}
+
+// timeWindow is a recurring window: a set of days, a range of hours within the day, a range of dates and a time zone.
+// example: "Mon-Fri 01:00-04:00 Europe/Berlin"
+type timeWindow struct {
+	days     [7]bool
+	from, to int // minutes from midnight. to<=from when the window crosses midnight
+	dateFrom string
+	dateTo   string
+	location *time.Location
+}
+
+// parseTimeWindow parses a window spec. The spec has space-separated parts (all of them are optional):
+// days ("Mon-Fri", "Sat,Sun"), hours ("01:00-04:00", the end is exclusive), dates ("2026-01-01..2026-12-31", inclusive) and
+// a time zone ("Europe/Berlin", UTC by default).
+func parseTimeWindow(spec string) (*timeWindow, error) {
+	w := &timeWindow{to: 24 * 60, location: time.UTC}
+	for i := range w.days {
+		w.days[i] = true
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty time window (example: Mon-Fri 01:00-04:00 Europe/Berlin)")
+	}
+	var err error
+	hasDays, hasHours, hasDates, hasZone := false, false, false, false
+	for _, field := range fields {
+		switch {
+		case strings.Contains(field, ".."):
+			if hasDates {
+				return nil, fmt.Errorf("time window %q has two date ranges", spec)
+			}
+			hasDates = true
+			err = w.parseDates(field)
+		case strings.Contains(field, ":"):
+			if hasHours {
+				return nil, fmt.Errorf("time window %q has two hour ranges", spec)
+			}
+			hasHours = true
+			err = w.parseHours(field)
+		case isDaysField(field):
+			if hasDays {
+				return nil, fmt.Errorf("time window %q has two day sets", spec)
+			}
+			hasDays = true
+			w.days, err = parseDays(field)
+		default:
+			if hasZone {
+				return nil, fmt.Errorf("time window %q: unknown part %q", spec, field)
+			}
+			hasZone = true
+			w.location, err = loadTimeZone(field)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+func (w *timeWindow) parseHours(field string) error {
+	parts := strings.Split(field, "-")
+	if len(parts) != 2 {
+		return fmt.Errorf("%q is not a range of hours (example: 01:00-04:00)", field)
+	}
+	var err error
+	w.from, err = parseClock(parts[0])
+	if err == nil {
+		w.to, err = parseClock(parts[1])
+	}
+	if err != nil || w.from == 24*60 {
+		return fmt.Errorf("%q is not a range of hours (example: 01:00-04:00)", field)
+	}
+	return nil
+}
+
+// parseClock returns the minutes from midnight of "HH:MM" (24:00 is the end of the day)
+func parseClock(str string) (int, error) {
+	t, err := time.Parse("15:04", str)
+	if err != nil {
+		if str == "24:00" {
+			return 24 * 60, nil
+		}
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func (w *timeWindow) parseDates(field string) error {
+	parts := strings.Split(field, "..")
+	if len(parts) != 2 {
+		return fmt.Errorf("%q is not a range of dates (example: 2026-01-01..2026-12-31)", field)
+	}
+	for _, part := range parts {
+		if _, err := time.Parse(dateLayout, part); err != nil {
+			return fmt.Errorf("%q is not a range of dates (example: 2026-01-01..2026-12-31)", field)
+		}
+	}
+	if parts[0] > parts[1] {
+		return fmt.Errorf("%q is an empty range of dates", field)
+	}
+	w.dateFrom, w.dateTo = parts[0], parts[1]
+	return nil
+}
+
+func isDaysField(field string) bool {
+	for _, part := range strings.FieldsFunc(field, func(r rune) bool { return r == ',' || r == '-' }) {
+		if _, ok := dayNames[strings.ToLower(part)]; !ok {
+			return false
+		}
+	}
+	return field != ""
+}
+
+// parseDays parses a list of days and ranges of days ("Mon-Fri", "Mon,Wed,Fri", "Fri-Mon")
+func parseDays(field string) (days [7]bool, err error) {
+	for _, part := range strings.Split(field, ",") {
+		bounds := strings.Split(part, "-")
+		if len(bounds) > 2 {
+			return days, fmt.Errorf("%q is not a set of days (example: Mon-Fri)", field)
+		}
+		first, ok1 := dayNames[strings.ToLower(bounds[0])]
+		last, ok2 := dayNames[strings.ToLower(bounds[len(bounds)-1])]
+		if !ok1 || !ok2 {
+			return days, fmt.Errorf("%q is not a set of days (example: Mon-Fri)", field)
+		}
+		for d := first; ; d = (d + 1) % 7 {
+			days[d] = true
+			if d == last {
+				break
+			}
+		}
+	}
+	return days, nil
+}
+
+// contains tests that the time is in the window. When the hours cross midnight (for example 22:00-02:00) the part
+// after midnight belongs to the window of the previous day (both for the days and for the dates).
+func (w *timeWindow) contains(t time.Time) bool {
+	t = t.In(w.location)
+	minutes := t.Hour()*60 + t.Minute()
+	day := t
+	if w.to > w.from {
+		if minutes < w.from || minutes >= w.to {
+			return false
+		}
+	} else { // crosses midnight
+		if minutes < w.from && minutes >= w.to {
+			return false
+		}
+		if minutes < w.to {
+			day = t.AddDate(0, 0, -1)
+		}
+	}
+	if !w.days[day.Weekday()] {
+		return false
+	}
+	if w.dateFrom != "" {
+		date := day.Format(dateLayout) // the date of the day the window opened
+		if date < w.dateFrom || date > w.dateTo {
+			return false
+		}
+	}
+	return true
+}
+
+// cronSchedule is a cron expression with five fields (minute, hour, day of month, month, day of week) and an optional time zone.
+// example: "* 1-3 * * Mon-Fri Europe/Berlin" (every minute from 01:00 to 03:59 on work days in Berlin)
+type cronSchedule struct {
+	minutes, hours, daysOfMonth, months, daysOfWeek []bool
+	anyDayOfMonth, anyDayOfWeek                     bool
+	location                                        *time.Location
+}
+
+func parseCronSchedule(spec string) (*cronSchedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 && len(fields) != 6 {
+		return nil, fmt.Errorf("%q is not a cron expression with 5 fields and an optional time zone (example: * 1-3 * * Mon-Fri Europe/Berlin)", spec)
+	}
+	s := &cronSchedule{location: time.UTC}
+	var err error
+	if s.minutes, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, err
+	}
+	if s.hours, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, err
+	}
+	if s.daysOfMonth, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, err
+	}
+	if s.months, err = parseCronField(fields[3], 1, 12, nil); err != nil {
+		return nil, err
+	}
+	if s.daysOfWeek, err = parseCronField(fields[4], 0, 7, dayNames); err != nil {
+		return nil, err
+	}
+	s.daysOfWeek[0] = s.daysOfWeek[0] || s.daysOfWeek[7] // 7 is Sunday
+	s.anyDayOfMonth = fields[2] == "*"
+	s.anyDayOfWeek = fields[4] == "*"
+	if len(fields) == 6 {
+		if s.location, err = loadTimeZone(fields[5]); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// parseCronField parses a list of values, ranges and steps ("*", "5", "1-5", "*/15", "0-30/10", "5/15", "1,15").
+// As in cron, a value with a step is a range from the value to the maximum ("5/15" is 5,20,35,50 in the minutes).
+func parseCronField(field string, min, max int, names map[string]time.Weekday) ([]bool, error) {
+	values := make([]bool, max+1)
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		hasStep := false
+		if i := strings.Index(part, "/"); i >= 0 {
+			hasStep = true
+			var err error
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step <= 0 {
+				return nil, fmt.Errorf("cron field %q has a wrong step", field)
+			}
+			part = part[:i]
+		}
+		first, last := min, max
+		if part != "*" {
+			bounds := strings.Split(part, "-")
+			if len(bounds) > 2 {
+				return nil, fmt.Errorf("cron field %q has a wrong range", field)
+			}
+			var err error
+			if first, err = parseCronValue(bounds[0], names); err != nil {
+				return nil, fmt.Errorf("cron field %q: %v", field, err)
+			}
+			last = first
+			if hasStep {
+				last = max
+			}
+			if len(bounds) == 2 {
+				if last, err = parseCronValue(bounds[1], names); err != nil {
+					return nil, fmt.Errorf("cron field %q: %v", field, err)
+				}
+			}
+		}
+		if first < min || last > max || first > last {
+			return nil, fmt.Errorf("cron field %q is out of range %v-%v", field, min, max)
+		}
+		for v := first; v <= last; v += step {
+			values[v] = true
+		}
+	}
+	return values, nil
+}
+
+func parseCronValue(str string, names map[string]time.Weekday) (int, error) {
+	if day, ok := names[strings.ToLower(str)]; ok {
+		return int(day), nil
+	}
+	return strconv.Atoi(str)
+}
+
+// contains tests that the time matches the expression. As in cron, when both the day of month and the day of week are
+// restricted the time matches if either of them matches.
+func (s *cronSchedule) contains(t time.Time) bool {
+	t = t.In(s.location)
+	if !s.minutes[t.Minute()] || !s.hours[t.Hour()] || !s.months[t.Month()] {
+		return false
+	}
+	dayOfMonth := s.daysOfMonth[t.Day()]
+	dayOfWeek := s.daysOfWeek[t.Weekday()]
+	switch {
+	case s.anyDayOfMonth && s.anyDayOfWeek:
+		return true
+	case s.anyDayOfMonth:
+		return dayOfWeek
+	case s.anyDayOfWeek:
+		return dayOfMonth
+	}
+	return dayOfMonth || dayOfWeek
+}
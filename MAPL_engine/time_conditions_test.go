@@ -0,0 +1,306 @@
+package MAPL_engine
+
+import (
+	"testing"
+	"time"
+)
+
+func mustParseTime(str string) time.Time {
+	t, err := time.Parse(time.RFC3339, str)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestParseTimeValue(t *testing.T) {
+	tests := []struct {
+		value    string
+		from, to string
+	}{
+		{"2026-12-31T18:00:00Z", "2026-12-31T18:00:00Z", "2026-12-31T18:00:00.000000001Z"},
+		{"2026-12-31T18:00:00+02:00", "2026-12-31T16:00:00Z", "2026-12-31T16:00:00.000000001Z"},
+		{"2026-12-31", "2026-12-31T00:00:00Z", "2027-01-01T00:00:00Z"},
+		{" 2026-12-31 Europe/Berlin ", "2026-12-30T23:00:00Z", "2026-12-31T23:00:00Z"},
+		{"2026-03-29 Europe/Berlin", "2026-03-28T23:00:00Z", "2026-03-29T22:00:00Z"}, // a day of 23 hours (daylight saving time starts)
+	}
+	for _, test := range tests {
+		from, to, err := parseTimeValue(test.value)
+		if err != nil {
+			t.Errorf("%q: %v", test.value, err)
+			continue
+		}
+		if !from.Equal(mustParseTime(test.from)) || !to.Equal(mustParseTime(test.to)) {
+			t.Errorf("%q: unexpected range %v - %v", test.value, from, to)
+		}
+	}
+
+	for _, value := range []string{"", "yesterday", "2026-13-01", "2026-12-31T25:00:00Z", "2026-12-31 Mars/Base", "2026-12-31 Local", "2026-12-31 UTC UTC"} {
+		if _, _, err := parseTimeValue(value); err == nil {
+			t.Errorf("%q: expected an error", value)
+		}
+	}
+}
+
+func TestParseTimeRange(t *testing.T) {
+	tests := []struct {
+		value    string
+		from, to string
+	}{
+		{"2026-01-01;2026-03-31", "2026-01-01T00:00:00Z", "2026-04-01T00:00:00Z"},
+		{"2026-01-01T10:00:00Z; 2026-01-01T12:00:00Z", "2026-01-01T10:00:00Z", "2026-01-01T12:00:00.000000001Z"},
+		{"2026-01-01 Europe/Berlin;2026-01-01", "2025-12-31T23:00:00Z", "2026-01-02T00:00:00Z"},
+		{"2026-01-01;2026-01-01", "2026-01-01T00:00:00Z", "2026-01-02T00:00:00Z"},
+	}
+	for _, test := range tests {
+		from, to, err := parseTimeRange(test.value)
+		if err != nil {
+			t.Errorf("%q: %v", test.value, err)
+			continue
+		}
+		if !from.Equal(mustParseTime(test.from)) || !to.Equal(mustParseTime(test.to)) {
+			t.Errorf("%q: unexpected range %v - %v", test.value, from, to)
+		}
+	}
+
+	for _, value := range []string{"", "2026-01-01", "2026-03-31;2026-01-01", "2026-01-01;2026-02-01;2026-03-01", "2026-01-01;soon", "2026-01-01T12:00:00Z;2026-01-01T10:00:00Z"} {
+		if _, _, err := parseTimeRange(value); err == nil {
+			t.Errorf("%q: expected an error", value)
+		}
+	}
+}
+
+// TestCompareTime tests the methods of time attributes with timestamps, dates and ranges of dates
+func TestCompareTime(t *testing.T) {
+	tests := []struct {
+		method, value, time string
+		result              bool
+	}{
+		{"EQ", "2026-11-02", "2026-11-02T00:00:00Z", true},
+		{"EQ", "2026-11-02", "2026-11-02T23:59:59Z", true},
+		{"EQ", "2026-11-02", "2026-11-03T00:00:00Z", false},
+		{"EQ", "2026-11-02", "2026-11-01T23:59:59Z", false},
+		{"eq", "2026-11-02 America/New_York", "2026-11-02T05:00:00Z", true},
+		{"EQ", "2026-11-02 America/New_York", "2026-11-02T04:59:00Z", false},
+		{"EQ", "2026-11-02T10:00:00Z", "2026-11-02T10:00:00Z", true},
+		{"EQ", "2026-11-02T10:00:00Z", "2026-11-02T10:00:01Z", false},
+		{"NE", "2026-11-02", "2026-11-03T00:00:00Z", true},
+		{"NEQ", "2026-11-02", "2026-11-02T12:00:00Z", false},
+		{"BEFORE", "2026-12-31", "2026-12-30T23:59:59Z", true},
+		{"BEFORE", "2026-12-31", "2026-12-31T00:00:00Z", false},
+		{"AFTER", "2026-12-31", "2026-12-31T12:00:00Z", false}, // after the end of the date
+		{"AFTER", "2026-12-31", "2027-01-01T00:00:00Z", true},
+		{"BETWEEN", "2026-01-01;2026-03-31", "2026-01-01T00:00:00Z", true},
+		{"BETWEEN", "2026-01-01;2026-03-31", "2026-03-31T23:59:59Z", true},
+		{"BETWEEN", "2026-01-01;2026-03-31", "2025-12-31T23:59:59Z", false},
+		{"between", "2026-01-01;2026-03-31", "2026-04-01T00:00:00Z", false},
+		{"NBETWEEN", "2026-01-01;2026-03-31", "2026-04-01T00:00:00Z", true},
+		{"NBETWEEN", "2026-01-01;2026-03-31", "2026-02-01T00:00:00Z", false},
+		{"IN_WINDOW", "Mon-Fri 09:00-17:00", "2026-11-02T10:00:00Z", true},
+		{"NOT_IN_WINDOW", "Mon-Fri 09:00-17:00", "2026-11-02T10:00:00Z", false},
+		{"CRON", "* 9-16 * * Mon-Fri", "2026-11-01T10:00:00Z", false},
+		{"NCRON", "* 9-16 * * Mon-Fri", "2026-11-01T10:00:00Z", true},
+	}
+	for _, test := range tests {
+		c := &Condition{Attribute: "requestTimestamp", Method: test.method, Value: test.value}
+		if err := convertTimeValue(c); err != nil {
+			t.Errorf("%v %q: %v", test.method, test.value, err)
+			continue
+		}
+		if result := compareTimeFunc(mustParseTime(test.time), c); result != test.result {
+			t.Errorf("%v %q at %v: expected %v", test.method, test.value, test.time, test.result)
+		}
+	}
+}
+
+func TestParseDays(t *testing.T) {
+	tests := []struct {
+		field string
+		days  []time.Weekday
+	}{
+		{"Mon-Fri", []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}},
+		{"Sat,Sun", []time.Weekday{time.Saturday, time.Sunday}},
+		{"Fri-Mon", []time.Weekday{time.Friday, time.Saturday, time.Sunday, time.Monday}}, // wraps around the week
+		{"mon,WED,Fri", []time.Weekday{time.Monday, time.Wednesday, time.Friday}},
+		{"Sun-Sun", []time.Weekday{time.Sunday}},
+		{"Sat-Sun,Tue", []time.Weekday{time.Saturday, time.Sunday, time.Tuesday}},
+	}
+	for _, test := range tests {
+		days, err := parseDays(test.field)
+		if err != nil {
+			t.Errorf("%q: %v", test.field, err)
+			continue
+		}
+		var expected [7]bool
+		for _, day := range test.days {
+			expected[day] = true
+		}
+		if days != expected {
+			t.Errorf("%q: unexpected days %v", test.field, days)
+		}
+	}
+
+	for _, field := range []string{"", "Mon-Tue-Wed", "Mon-Funday", "Mon,", "Monday"} {
+		if _, err := parseDays(field); err == nil {
+			t.Errorf("%q: expected an error", field)
+		}
+	}
+}
+
+func TestTimeWindow(t *testing.T) {
+	tests := []struct {
+		spec, time string
+		contains   bool
+	}{
+		{"Mon-Fri 01:00-04:00", "2026-11-02T01:00:00Z", true}, // Monday
+		{"Mon-Fri 01:00-04:00", "2026-11-02T03:59:59Z", true},
+		{"Mon-Fri 01:00-04:00", "2026-11-02T04:00:00Z", false}, // the end is exclusive
+		{"Mon-Fri 01:00-04:00", "2026-11-02T00:59:00Z", false},
+		{"Mon-Fri 01:00-04:00", "2026-11-01T02:00:00Z", false}, // Sunday
+		{"Mon 20:00-24:00", "2026-11-02T23:59:00Z", true},
+		{"Mon 20:00-24:00", "2026-11-03T00:00:00Z", false},
+
+		// wrapped ranges of days
+		{"Fri-Mon", "2026-10-30T12:00:00Z", true}, // Friday
+		{"Fri-Mon", "2026-10-31T12:00:00Z", true},
+		{"Fri-Mon", "2026-11-02T12:00:00Z", true},
+		{"Fri-Mon", "2026-11-03T12:00:00Z", false}, // Tuesday
+
+		// windows that cross midnight belong to the day they opened
+		{"Fri 22:00-02:00", "2026-01-02T22:00:00Z", true}, // Friday
+		{"Fri 22:00-02:00", "2026-01-03T01:00:00Z", true}, // Saturday, in the window of Friday
+		{"Fri 22:00-02:00", "2026-01-03T02:00:00Z", false},
+		{"Fri 22:00-02:00", "2026-01-03T22:00:00Z", false},
+		{"Fri 22:00-02:00", "2026-01-02T01:00:00Z", false},                       // Friday, in the window of Thursday
+		{"Fri 22:00-02:00 2026-01-01..2026-01-02", "2026-01-03T01:00:00Z", true}, // the window opened on January 2
+		{"Fri 22:00-02:00 2026-01-01..2026-01-02", "2026-01-09T23:00:00Z", false},
+		{"22:00-02:00 2026-01-01..2026-01-01", "2026-01-02T01:00:00Z", true},
+		{"22:00-02:00 2026-01-01..2026-01-01", "2026-01-01T01:00:00Z", false}, // the window opened on December 31
+
+		// date ranges (inclusive)
+		{"Sat,Sun 2026-11-01..2026-11-30", "2026-11-01T10:00:00Z", true},
+		{"Sat,Sun 2026-11-01..2026-11-30", "2026-11-29T23:59:00Z", true},
+		{"Sat,Sun 2026-11-01..2026-11-30", "2026-10-31T10:00:00Z", false},
+		{"Sat,Sun 2026-11-01..2026-11-30", "2026-11-30T10:00:00Z", false}, // Monday
+		{"2026-11-01..2026-11-30 Europe/Berlin", "2026-10-31T23:30:00Z", true},
+		{"2026-11-01..2026-11-30 Europe/Berlin", "2026-11-30T23:30:00Z", false},
+
+		// time zones and daylight saving time (Europe/Berlin changes at 01:00 UTC on 2026-03-29 and 2026-10-25)
+		{"02:00-03:00 Europe/Berlin", "2026-03-28T01:30:00Z", true},  // 02:30 CET
+		{"02:00-03:00 Europe/Berlin", "2026-03-29T00:30:00Z", false}, // 01:30 CET
+		{"02:00-03:00 Europe/Berlin", "2026-03-29T01:30:00Z", false}, // 03:30 CEST
+		{"02:00-03:00 Europe/Berlin", "2026-03-30T00:30:00Z", true},  // 02:30 CEST
+		{"01:00-04:00 Europe/Berlin", "2026-10-25T00:30:00Z", true},  // 02:30 CEST
+		{"01:00-04:00 Europe/Berlin", "2026-10-25T01:30:00Z", true},  // 02:30 CET
+		{"01:00-04:00 Europe/Berlin", "2026-10-25T03:00:00Z", false}, // 04:00 CET
+		{"Thu 01:00-04:00 Europe/Berlin", "2026-07-01T23:30:00Z", true},
+		{"Thu 01:00-04:00", "2026-07-01T23:30:00Z", false},
+	}
+	for _, test := range tests {
+		w, err := parseTimeWindow(test.spec)
+		if err != nil {
+			t.Errorf("%q: %v", test.spec, err)
+			continue
+		}
+		if contains := w.contains(mustParseTime(test.time)); contains != test.contains {
+			t.Errorf("%q at %v: expected %v", test.spec, test.time, test.contains)
+		}
+	}
+
+	for _, spec := range []string{
+		"",
+		"Mon-Fri Tue",
+		"01:00-02:00 03:00-04:00",
+		"2026-01-01..2026-01-02 2026-02-01..2026-02-02",
+		"01:00",
+		"01:00-02:00-03:00",
+		"25:00-26:00",
+		"24:00-02:00",
+		"2026-02-01..2026-01-01",
+		"2026-01-01..2026-13-01",
+		"2026-01-01...2026-01-02",
+		"Mon-Fri Mars/Base",
+		"Europe/Berlin UTC",
+		"Local",
+	} {
+		if _, err := parseTimeWindow(spec); err == nil {
+			t.Errorf("%q: expected an error", spec)
+		}
+	}
+}
+
+func TestCronSchedule(t *testing.T) {
+	tests := []struct {
+		spec, time string
+		contains   bool
+	}{
+		{"* * * * *", "2026-11-02T10:07:00Z", true},
+		{"*/15 * * * *", "2026-11-02T10:45:00Z", true},
+		{"*/15 * * * *", "2026-11-02T10:50:00Z", false},
+		{"5/15 * * * *", "2026-11-02T10:05:00Z", true}, // 5, 20, 35 and 50
+		{"5/15 * * * *", "2026-11-02T10:20:00Z", true},
+		{"5/15 * * * *", "2026-11-02T10:50:00Z", true},
+		{"5/15 * * * *", "2026-11-02T10:00:00Z", false},
+		{"5/15 * * * *", "2026-11-02T10:10:00Z", false},
+		{"0-30/10 9 * * *", "2026-11-02T09:30:00Z", true},
+		{"0-30/10 9 * * *", "2026-11-02T09:40:00Z", false},
+		{"0-30/10 9 * * *", "2026-11-02T10:00:00Z", false},
+		{"0 9 1,15 * *", "2026-11-15T09:00:00Z", true},
+		{"0 9 1,15 * *", "2026-11-16T09:00:00Z", false},
+		{"0 9 * 12 *", "2026-12-01T09:00:00Z", true},
+		{"0 9 * 12 *", "2026-11-01T09:00:00Z", false},
+		{"0 9 * * Mon-Fri", "2026-11-02T09:00:00Z", true},
+		{"0 9 * * Mon-Fri", "2026-11-01T09:00:00Z", false},
+		{"0 0 * * 7", "2026-11-01T00:00:00Z", true}, // 7 is Sunday
+		{"0 0 * * 0", "2026-11-01T00:00:00Z", true},
+		{"0 0 * * 1/2", "2026-11-01T00:00:00Z", true}, // Mon, Wed, Fri and Sun (7)
+		{"0 0 * * 1/2", "2026-11-03T00:00:00Z", false},
+
+		// when both the day of month and the day of week are restricted either of them matches
+		{"0 9 13 * Fri", "2026-11-06T09:00:00Z", true}, // Friday
+		{"0 9 13 * Fri", "2026-12-13T09:00:00Z", true}, // Sunday the 13th
+		{"0 9 13 * Fri", "2026-11-12T09:00:00Z", false},
+		{"0 9 */2 * *", "2026-11-03T09:00:00Z", true},
+		{"0 9 */2 * *", "2026-11-02T09:00:00Z", false},
+
+		// time zones and daylight saving time (America/New_York changes on 2026-03-08, Europe/Berlin on 2026-03-29)
+		{"0 9 * * * America/New_York", "2026-03-06T14:00:00Z", true},
+		{"0 9 * * * America/New_York", "2026-03-09T13:00:00Z", true},
+		{"0 9 * * * America/New_York", "2026-03-09T14:00:00Z", false},
+		{"30 2 * * * Europe/Berlin", "2026-03-29T00:30:00Z", false}, // 02:30 does not exist on that day
+		{"30 2 * * * Europe/Berlin", "2026-03-29T01:30:00Z", false},
+		{"30 2 * * * Europe/Berlin", "2026-03-30T00:30:00Z", true},
+	}
+	for _, test := range tests {
+		s, err := parseCronSchedule(test.spec)
+		if err != nil {
+			t.Errorf("%q: %v", test.spec, err)
+			continue
+		}
+		if contains := s.contains(mustParseTime(test.time)); contains != test.contains {
+			t.Errorf("%q at %v: expected %v", test.spec, test.time, test.contains)
+		}
+	}
+
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"* * * * * UTC UTC",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"* * * * Funday",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"5-1 * * * *",
+		"1-2-3 * * * *",
+		"a * * * *",
+		"* * * * * Mars/Base",
+	} {
+		if _, err := parseCronSchedule(spec); err == nil {
+			t.Errorf("%q: expected an error", spec)
+		}
+	}
+}
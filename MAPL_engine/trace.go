@@ -0,0 +1,151 @@
+package MAPL_engine
+
+import (
+	"encoding/json"
+)
+
+// CheckTrace explains the decision of CheckWithTrace: for each rule, why it matched the message or did not.
+type CheckTrace struct {
+	MessageID           string        `json:"MessageID,omitempty"`
+	Decision            int           `json:"Decision"`
+	DecisionString      string        `json:"DecisionString"`
+	RelevantRuleIndex   int           `json:"RelevantRuleIndex"`
+	AppliedRulesIndices []int         `json:"AppliedRulesIndices"`
+	AuditResults        []AuditResult `json:"AuditResults,omitempty"` // the audit rules that apply to the message (see CheckWithAudit)
+	Rules               []RuleTrace   `json:"Rules"`
+}
+
+// RuleTrace describes the test of the message with one rule: each stage of CheckOneRule and the conditions.
+type RuleTrace struct {
+	RuleIndex     int                  `json:"RuleIndex"`
+	RuleID        string               `json:"RuleID,omitempty"`
+	Audit         bool                 `json:"Audit,omitempty"` // the rule is in audit mode (its result does not change the decision)
+	Result        int                  `json:"Result"`
+	ResultString  string               `json:"ResultString"`
+	Stages        []StageTrace         `json:"Stages"`
+	DNFConditions []ANDConditionsTrace `json:"DNFConditions,omitempty"`
+}
+
+// StageTrace is the result of one stage of CheckOneRule (validity, sender, receiver, operation, protocol, resourceType, resourceName or conditions).
+// The validity stage is only of rules with a validity period.
+type StageTrace struct {
+	Stage        string `json:"Stage"`
+	Match        bool   `json:"Match"`
+	MessageValue string `json:"MessageValue"`           // the value from the message
+	RulePattern  string `json:"RulePattern"`            // the pattern from the rule
+	NotEvaluated bool   `json:"NotEvaluated,omitempty"` // the conditions are not evaluated after a mismatch of another stage
+}
+
+// ANDConditionsTrace is the result of one ANDConditions clause
+type ANDConditionsTrace struct {
+	Result     bool             `json:"Result"`
+	Conditions []ConditionTrace `json:"Conditions"`
+}
+
+// ConditionTrace is the result of one condition
+type ConditionTrace struct {
+	Attribute    string `json:"Attribute"`
+	Method       string `json:"Method"`
+	Value        string `json:"Value"`
+	MessageValue string `json:"MessageValue"` // the value extracted from the message (empty if the attribute does not exist in the message)
+	Result       bool   `json:"Result"`
+}
+
+// CheckWithTrace is the same as the Check function (with the same outputs) and in addition returns a trace that explains the result of each rule.
+func CheckWithTrace(message *MessageAttributes, rules *Rules) (decision int, descisionString string, relevantRuleIndex int, results []int, appliedRulesIndices []int, trace CheckTrace) {
+
+	N := len(rules.Rules)
+
+	results = make([]int, N)
+	trace.Rules = make([]RuleTrace, N)
+	for i := range rules.Rules {
+		ruleTrace := &trace.Rules[i]
+		ruleTrace.RuleIndex = i
+		ruleTrace.RuleID = rules.Rules[i].RuleID
+		ruleTrace.Audit = IsAuditRule(&rules.Rules[i])
+		ruleTrace.Stages = []StageTrace{}
+
+		results[i] = checkOneRule(message, &rules.Rules[i], ruleTrace)
+
+		ruleTrace.Result = results[i]
+		ruleTrace.ResultString = ActionTypeNames[results[i]]
+	}
+
+	decision, descisionString, relevantRuleIndex, appliedRulesIndices = combineResults(rules, results)
+
+	trace.MessageID = message.MessageID
+	trace.Decision = decision
+	trace.DecisionString = descisionString
+	trace.RelevantRuleIndex = relevantRuleIndex
+	trace.AppliedRulesIndices = appliedRulesIndices
+	trace.AuditResults = newAuditResults(rules, results, decision)
+
+	return decision, descisionString, relevantRuleIndex, results, appliedRulesIndices, trace
+}
+
+// ToJson converts a structure into a json string
+func (trace CheckTrace) ToJson() string { // method of GeneralStruct interface
+	jsonBytes, err := json.MarshalIndent(trace, "", "  ")
+	if err != nil {
+		panic("error converting to json")
+	}
+	return (string(jsonBytes))
+}
+
+// addStage adds the result of one stage to the trace (if not nil)
+func (trace *RuleTrace) addStage(stage string, match bool, messageValue, rulePattern string) {
+	if trace == nil {
+		return
+	}
+	trace.Stages = append(trace.Stages, StageTrace{Stage: stage, Match: match, MessageValue: messageValue, RulePattern: rulePattern})
+}
+
+// addNotEvaluatedStage adds a stage that was not evaluated to the trace (if not nil)
+func (trace *RuleTrace) addNotEvaluatedStage(stage string) {
+	if trace == nil {
+		return
+	}
+	trace.Stages = append(trace.Stages, StageTrace{Stage: stage, NotEvaluated: true})
+}
+
+func newConditionTrace(c *Condition, messageValue string, result bool) ConditionTrace {
+	attribute := c.OriginalAttribute
+	if attribute == "" {
+		attribute = c.Attribute
+	}
+	value := c.OriginalValue
+	if value == "" {
+		value = c.Value
+	}
+	return ConditionTrace{Attribute: attribute, Method: c.Method, Value: value, MessageValue: messageValue, Result: result}
+}
+
+// senderMessageValue returns the message attribute that is compared with the rule's sender
+func senderMessageValue(rule *Rule, message *MessageAttributes) string {
+	switch rule.Sender.SenderType {
+	case "subnet":
+		return message.SourceIp
+	case "namespace":
+		return message.SourceNamespace
+	case "principal":
+		return message.SourcePrincipal
+	case "labels":
+		return formatLabels(message.SourceLabels)
+	}
+	return message.SourceService
+}
+
+// receiverMessageValue returns the message attribute that is compared with the rule's receiver
+func receiverMessageValue(rule *Rule, message *MessageAttributes) string {
+	switch rule.Receiver.ReceiverType {
+	case "subnet":
+		return message.DestinationIp
+	case "namespace":
+		return message.DestinationNamespace
+	case "principal":
+		return message.DestinationPrincipal
+	case "labels":
+		return formatLabels(message.DestinationLabels)
+	}
+	return message.DestinationService
+}
@@ -0,0 +1,60 @@
+package MAPL_engine
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestCheckWithTraceExamples tests that CheckWithTrace has the same outputs as Check for the messages of each pair of example files
+func TestCheckWithTraceExamples(t *testing.T) {
+	for _, pair := range examplePairs {
+		rules, err := ParseRulesFromFile(pair[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		messages, err := ParseMessagesFromFile(pair[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range messages.Messages {
+			decision, _, relevantRuleIndex, results, appliedRulesIndices := Check(&messages.Messages[i], rules)
+			traceDecision, _, traceRelevantRuleIndex, traceResults, traceAppliedRulesIndices, trace := CheckWithTrace(&messages.Messages[i], rules)
+			if traceDecision != decision || traceRelevantRuleIndex != relevantRuleIndex || !reflect.DeepEqual(traceResults, results) ||
+				!reflect.DeepEqual(traceAppliedRulesIndices, appliedRulesIndices) || trace.Decision != decision {
+				t.Errorf("%v message #%v: CheckWithTrace differs from Check", pair[1], i)
+			}
+		}
+	}
+}
+
+// TestCheckWithTraceConditionsNotEvaluated tests that the conditions are not evaluated (and custom functions are not called) after a mismatch
+func TestCheckWithTraceConditionsNotEvaluated(t *testing.T) {
+	calls := 0
+	err := RegisterConditionFunc("testCount", func(m *MessageAttributes, c Condition) (bool, error) {
+		calls++
+		return true, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer UnregisterConditionFunc("testCount")
+	rules, err := ParseRules([]byte(customRules("testCount")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	message := customMessage("/books")
+	message.SourceService = "C.my_namespace" // the sender does not match
+	Check(message, rules)
+	decision, _, _, _, _, trace := CheckWithTrace(message, rules)
+	stages := trace.Rules[0].Stages
+	last := stages[len(stages)-1]
+	if calls != 0 || decision != DEFAULT || last.Stage != "conditions" || !last.NotEvaluated || stages[0].Match || trace.Rules[0].DNFConditions != nil {
+		t.Errorf("unexpected trace (%v calls): %+v", calls, trace.Rules[0])
+	}
+
+	_, _, _, _, _, trace = CheckWithTrace(customMessage("/books"), rules)
+	if calls != 1 || trace.Decision != ALLOW || len(trace.Rules[0].DNFConditions) != 1 {
+		t.Errorf("unexpected trace (%v calls): %+v", calls, trace.Rules[0])
+	}
+}
@@ -0,0 +1,160 @@
+package MAPL_engine
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// the yaml node tree (yaml.v3) is used to find the line and column of fields when reporting errors.
+
+var reYamlErrorLine = regexp.MustCompile(`line (\d+)`)
+
+// yamlErrorLine extracts the line number from a yaml error message (example: "yaml: line 3: mapping values are not allowed in this context")
+func yamlErrorLine(str string) int {
+	m := reYamlErrorLine.FindStringSubmatch(str)
+	if m == nil {
+		return 0
+	}
+	line, err := strconv.Atoi(m[1])
+	if err != nil {
+		return 0
+	}
+	return line
+}
+
+// yamlDecodeErrors returns the error messages of a yaml decoding error (one per problem)
+func yamlDecodeErrors(err error) []string {
+	if typeErr, ok := err.(*yamlv3.TypeError); ok {
+		return typeErr.Errors
+	}
+	return []string{err.Error()}
+}
+
+// yamlDocuments returns the node trees of the yaml documents in the data (separated by ---). Empty documents are skipped.
+func yamlDocuments(data []byte) ([]*yamlv3.Node, error) {
+	documents := []*yamlv3.Node{}
+	decoder := yamlv3.NewDecoder(bytes.NewReader(data))
+	for {
+		var root yamlv3.Node
+		err := decoder.Decode(&root)
+		if err == io.EOF {
+			return documents, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if top := yamlTopNode(&root); top == nil || top.Tag == "!!null" { // for example after a --- at the end of the data
+			continue
+		}
+		documents = append(documents, &root)
+	}
+}
+
+// yamlTopNode returns the top level node of the document
+func yamlTopNode(root *yamlv3.Node) *yamlv3.Node {
+	if root == nil {
+		return nil
+	}
+	if root.Kind == yamlv3.DocumentNode {
+		if len(root.Content) == 0 {
+			return nil
+		}
+		return root.Content[0]
+	}
+	return root
+}
+
+// yamlMappingValue returns the value node of the key in a mapping node (nil if not found)
+func yamlMappingValue(node *yamlv3.Node, key string) *yamlv3.Node {
+	if node == nil || node.Kind != yamlv3.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// yamlNodeAt returns the node at the path (example: rules[3].DNFconditions[0].ANDconditions[1].value).
+// If the path does not exist in the yaml input then the deepest existing node on the path is returned.
+func yamlNodeAt(root *yamlv3.Node, path string) *yamlv3.Node {
+	node := yamlTopNode(root)
+	if node == nil || path == "" {
+		return node
+	}
+	for _, segment := range strings.Split(path, ".") {
+		key := segment
+		indices := []int{}
+		if i := strings.Index(segment, "["); i >= 0 {
+			key = segment[:i]
+			for _, str := range strings.Split(strings.Trim(segment[i:], "[]"), "][") {
+				index, err := strconv.Atoi(str)
+				if err != nil {
+					return node
+				}
+				indices = append(indices, index)
+			}
+		}
+		if key != "" {
+			next := yamlMappingValue(node, key)
+			if next == nil {
+				return node
+			}
+			node = next
+		}
+		for _, index := range indices {
+			if node.Kind != yamlv3.SequenceNode || index >= len(node.Content) {
+				return node
+			}
+			node = node.Content[index]
+		}
+	}
+	return node
+}
+
+// yamlPosition returns the line and column of the field at the path (0,0 if unknown)
+func yamlPosition(root *yamlv3.Node, path string) (line, column int) {
+	node := yamlNodeAt(root, path)
+	if node == nil {
+		return 0, 0
+	}
+	return node.Line, node.Column
+}
+
+// yamlItemIndexAtLine returns the index of the item of the top level list (for example "rules") that contains the line (-1 if not found)
+func yamlItemIndexAtLine(root *yamlv3.Node, key string, line int) int {
+	list := yamlMappingValue(yamlTopNode(root), key)
+	if list == nil || list.Kind != yamlv3.SequenceNode {
+		return -1
+	}
+	index := -1
+	for i, item := range list.Content {
+		if item.Line <= line {
+			index = i
+		}
+	}
+	return index
+}
+
+// yamlColumnAtLine returns the column of the first node (under the given node) that starts at the line (0 if not found)
+func yamlColumnAtLine(node *yamlv3.Node, line int) int {
+	if node == nil {
+		return 0
+	}
+	if node.Line == line && node.Kind != yamlv3.DocumentNode {
+		return node.Column
+	}
+	for _, child := range node.Content {
+		if column := yamlColumnAtLine(child, line); column > 0 {
+			return column
+		}
+	}
+	return 0
+}
@@ -0,0 +1,163 @@
+package MAPL_engine
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// strict decoding: every key in the yaml input must be a field of the structure it is decoded into (misspelled keys such as "reciever" are errors).
+
+// yamlUnknownField checks that all of the keys in the yaml node tree are fields of the structure v (or of its nested structures).
+// For the first unknown key it returns its path (example: rules[2].reciever), its line and column and an ErrUnknownField error.
+// It returns a nil error if all of the keys are known.
+func yamlUnknownField(root *yamlv3.Node, v interface{}) (path string, line, column int, err error) {
+	node := yamlTopNode(root)
+	if node == nil {
+		return "", 0, 0, nil
+	}
+	keyNode, path, err := yamlCheckKeys(node, reflect.TypeOf(v), "")
+	if err != nil {
+		return path, keyNode.Line, keyNode.Column, err
+	}
+	return "", 0, 0, nil
+}
+
+// yamlCheckKeys checks the keys of the node with the type t. It returns the node of the first unknown key, its path and the error.
+func yamlCheckKeys(node *yamlv3.Node, t reflect.Type, path string) (*yamlv3.Node, string, error) {
+	if node.Kind == yamlv3.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yamlv3.MappingNode {
+			return nil, "", nil
+		}
+		fields := map[string]reflect.Type{}
+		yamlStructFields(t, fields)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			if key == "<<" { // merge key
+				continue
+			}
+			keyPath := joinYamlPath(path, key)
+			fieldType, ok := fields[key]
+			if !ok {
+				return node.Content[i], keyPath, unknownFieldError(key, fields)
+			}
+			if keyNode, p, err := yamlCheckKeys(node.Content[i+1], fieldType, keyPath); err != nil {
+				return keyNode, p, err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if node.Kind != yamlv3.SequenceNode {
+			return nil, "", nil
+		}
+		for i, item := range node.Content {
+			if keyNode, p, err := yamlCheckKeys(item, t.Elem(), fmt.Sprintf("%v[%v]", path, i)); err != nil {
+				return keyNode, p, err
+			}
+		}
+	case reflect.Map:
+		if node.Kind != yamlv3.MappingNode {
+			return nil, "", nil
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if keyNode, p, err := yamlCheckKeys(node.Content[i+1], t.Elem(), joinYamlPath(path, node.Content[i].Value)); err != nil {
+				return keyNode, p, err
+			}
+		}
+	}
+	return nil, "", nil
+}
+
+// yamlStructFields adds the yaml keys of the structure's fields (as decoded by the yaml package) and their types
+func yamlStructFields(t reflect.Type, fields map[string]reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous { // unexported
+			continue
+		}
+		tag := strings.Split(f.Tag.Get("yaml"), ",")
+		name := tag[0]
+		if name == "-" {
+			continue
+		}
+		inline := false
+		for _, flag := range tag[1:] {
+			inline = inline || flag == "inline"
+		}
+		if inline {
+			fieldType := f.Type
+			for fieldType.Kind() == reflect.Ptr {
+				fieldType = fieldType.Elem()
+			}
+			if fieldType.Kind() == reflect.Struct {
+				yamlStructFields(fieldType, fields)
+			}
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f.Type
+	}
+}
+
+func joinYamlPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// unknownFieldError returns the error of an unknown key with a known key with a similar name (if there is one)
+func unknownFieldError(key string, fields map[string]reflect.Type) error {
+	suggestion := ""
+	bestDistance := len(key)/2 + 1
+	for name := range fields {
+		distance := editDistance(strings.ToLower(key), strings.ToLower(name))
+		if distance < bestDistance || (distance == bestDistance && suggestion != "" && name < suggestion) {
+			suggestion = name
+			bestDistance = distance
+		}
+	}
+	if suggestion != "" && bestDistance <= 2 {
+		return fmt.Errorf("%w: %q (did you mean %q?)", ErrUnknownField, key, suggestion)
+	}
+	return fmt.Errorf("%w: %q", ErrUnknownField, key)
+}
+
+// editDistance is the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(minInt(previous[j]+1, current[j-1]+1), previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,78 @@
+package MAPL_engine
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const strictRules = `rules:
+  - rule_id: 0
+    sender:
+      senderName: "A.my_namespace"
+      senderType: service
+    receiver:
+      receiverName: "B.my_namespace"
+      receiverType: service
+    protocol: http
+    resource:
+      resourceType: httpPath
+      resourceName: "/books/*"
+    operation: GET
+    decision: allow
+`
+
+func TestParseRulesUnknownField(t *testing.T) {
+	_, err := ParseRules([]byte(strictRules))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		old, new   string
+		path       string
+		line       int
+		column     int
+		suggestion string
+	}{
+		{"    receiver:", "    reciever:", "rules[0].reciever", 6, 5, `"receiver"`},
+		{"    decision: allow", "    DNFcondition: []\n    decision: allow", "rules[0].DNFcondition", 14, 5, `"DNFconditions"`},
+		{"      resourceName:", "      resource_name:", "rules[0].resource.resource_name", 12, 7, `"resourceName"`},
+		{"    operation: GET", "    operation: GET\n    comment: books", "rules[0].comment", 14, 5, ""},
+	}
+	for _, test := range tests {
+		_, err := ParseRules([]byte(strings.Replace(strictRules, test.old, test.new, 1)))
+		ruleErr, ok := err.(*RuleError)
+		if !ok {
+			t.Fatalf("%v: expected a *RuleError, got %v", test.path, err)
+		}
+		if !errors.Is(err, ErrUnknownField) || ruleErr.Path != test.path || ruleErr.Line != test.line || ruleErr.Column != test.column || ruleErr.RuleID != "0" {
+			t.Errorf("%v: unexpected error: %v", test.path, err)
+		}
+		if !strings.Contains(err.Error(), test.suggestion) {
+			t.Errorf("%v: expected the suggestion %v in %v", test.path, test.suggestion, err)
+		}
+	}
+}
+
+func TestParseMessagesUnknownField(t *testing.T) {
+	data := "messages:\n  - message_id: 0\n    sender_name: A\n  - message_id: 1\n    receiver_nam: B\n"
+	_, err := ParseMessages([]byte(data))
+	messageErr, ok := err.(*MessageError)
+	if !ok || !errors.Is(err, ErrUnknownField) {
+		t.Fatalf("expected a *MessageError with ErrUnknownField, got %v", err)
+	}
+	if messageErr.MessageIndex != 1 || messageErr.Path != "messages[1].receiver_nam" || messageErr.Line != 5 || messageErr.Column != 5 {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestYamlReadOneRuleUnknownField(t *testing.T) {
+	defer func() {
+		ruleErr, ok := recover().(*RuleError)
+		if !ok || !errors.Is(ruleErr, ErrUnknownField) || ruleErr.Path != "sender.sendername" || ruleErr.Line != 3 {
+			t.Errorf("expected a panic with an unknown field error, got %v", ruleErr)
+		}
+	}()
+	YamlReadOneRule("rule_id: 0\nsender:\n  sendername: A\n")
+}
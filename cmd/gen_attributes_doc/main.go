@@ -0,0 +1,57 @@
+// gen_attributes_doc generates the supported attributes document (docs/SUPPORTED_ATTRIBUTES.md) from the attribute registry of the MAPL engine.
+// It is run by "go generate" in the MAPL_engine folder.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"strings"
+
+	"github.com/octarinesec/MAPL/MAPL_engine"
+)
+
+func main() {
+	output := flag.String("o", "docs/SUPPORTED_ATTRIBUTES.md", "output file")
+	flag.Parse()
+
+	err := ioutil.WriteFile(*output, supportedAttributesMarkdown(), 0644)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// supportedAttributesMarkdown writes the table of the registered attributes
+func supportedAttributesMarkdown() []byte {
+	var buf bytes.Buffer
+
+	buf.WriteString("<!-- Code generated by cmd/gen_attributes_doc from the attribute registry of the MAPL engine. DO NOT EDIT. -->\n\n")
+	buf.WriteString("# Attributes Supported in the Conditions  \n\n")
+	buf.WriteString("The following keywords are used in MAPL conditions with the value to-compare-with extracted from the message attributes.\n\n")
+	buf.WriteString("<br>\n\n")
+	buf.WriteString("| MAPL condition keyword | type | message attribute | methods | description |\n")
+	buf.WriteString("|:-------:|:-----:|:-----:|:-----:|:-----|\n")
+	for _, attribute := range MAPL_engine.Attributes() {
+		fmt.Fprintf(&buf, "| %v | %v | message.%v | %v | %v |\n", attribute.Keyword, attribute.Type, attribute.Field, strings.Join(attribute.Methods(), ", "), attribute.Description)
+	}
+	buf.WriteString("\n")
+	buf.WriteString("In addition, the following keywords are supported:\n\n")
+	buf.WriteString("| MAPL condition keyword | message attribute | methods | description |\n")
+	buf.WriteString("|:-------:|:-----:|:-----:|:-----|\n")
+	buf.WriteString("| senderLabel[key] | message.SourceLabels | EQ, NEQ, NE, RE, NRE, IN, NIN, EX, NEX | the value of the sender's label. The value may be receiverLabel[key2] (with EQ, NEQ, NE, EX, NEX) |\n")
+	buf.WriteString("| receiverLabel[key] | message.DestinationLabels | EQ, NEQ, NE, RE, NRE, IN, NIN, EX, NEX | the value of the receiver's label |\n")
+	buf.WriteString("| senderLabels | message.SourceLabels | SELECTOR, NSELECTOR, EX, NEX | the sender's labels tested with a label selector (for example app=web,tier in (front,edge),!canary) |\n")
+	buf.WriteString("| receiverLabels | message.DestinationLabels | SELECTOR, NSELECTOR, EX, NEX | the receiver's labels tested with a label selector |\n")
+	buf.WriteString("| true, false | | | a condition that is always true (or false) |\n")
+	buf.WriteString("\n")
+	buf.WriteString("Values of duration attributes have a unit (for example 250ms, 1.5s). Values of IP attributes are IPv4 or IPv6 addresses. ")
+	buf.WriteString("With the IN_CIDR and NOT_IN_CIDR methods the value is a list of CIDRs or IPs separated by ';' (for example 10.20.0.0/16;fd00:20::/64).  \n")
+	buf.WriteString("Values of time attributes are timestamps (2026-12-31T18:00:00Z) or dates with an optional time zone (2026-12-31 Europe/Berlin) for the EQ, NEQ, NE, BEFORE and AFTER methods, ")
+	buf.WriteString("recurring windows (Mon-Fri 01:00-04:00 Europe/Berlin) for the IN_WINDOW and NOT_IN_WINDOW methods and cron expressions (* 1-3 * * Mon-Fri Europe/Berlin) for the CRON and NCRON methods.  \n")
+	buf.WriteString("The values of the IN and NIN methods are lists separated by ';' and the values of the BETWEEN and NBETWEEN methods are ranges of two values (low;high, inclusive).  \n")
+	buf.WriteString("New keywords are added to the registry with `MAPL_engine.RegisterAttribute`.\n")
+
+	return buf.Bytes()
+}
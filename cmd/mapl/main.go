@@ -0,0 +1,586 @@
+// mapl is a command-line tool for MAPL rules.
+//
+// Usage:
+//
+//	mapl validate rules.yaml [rules2.yaml ...]
+//	mapl check --rules rules.yaml --messages messages.yaml [--json] [--fail-on alert|block|default]
+//	mapl explain --rules rules.yaml --messages messages.yaml [--index N | --message-id ID] [--json]
+//	mapl test --rules rules.yaml --messages messages.yaml [--json]
+//	mapl hash rules.yaml
+//	mapl diff [--json] old_rules.yaml new_rules.yaml
+//	mapl schema [-o file] rules|messages
+//	mapl migrate [-w | -o file] rules.yaml
+//	mapl list [--tag TAG] [--owner OWNER] [--expiring-within DAYS] [--json] rules.yaml
+//
+// Rules files are read with MAPL_engine.LoadBundle: files with the .json extension are read as json, all of the documents of yaml files are read
+// and directories and manifests of policy bundles are read as one rules file.
+//
+// Exit codes: 0 (success), 1 (invalid rules, differences, a decision of --fail-on or failed expectations), 2 (invalid arguments or files that cannot be read).
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/octarinesec/MAPL/MAPL_analysis"
+	"github.com/octarinesec/MAPL/MAPL_engine"
+)
+
+const (
+	exitOK    = 0
+	exitFound = 1 // invalid rules (validate, migrate), differences (diff), a decision of --fail-on (check) or failed expectations (test)
+	exitError = 2 // invalid arguments or files
+)
+
+const usage = `usage:
+  mapl validate rules.yaml [rules2.yaml ...]
+  mapl check --rules rules.yaml --messages messages.yaml [--json] [--fail-on alert|block|default]
+  mapl explain --rules rules.yaml --messages messages.yaml [--index N | --message-id ID] [--json]
+  mapl test --rules rules.yaml --messages messages.yaml [--json]
+  mapl hash rules.yaml
+  mapl diff [--json] old_rules.yaml new_rules.yaml
+  mapl schema [-o file] rules|messages
+  mapl migrate [-w | -o file] rules.yaml
+  mapl list [--tag TAG] [--owner OWNER] [--expiring-within DAYS] [--json] rules.yaml
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(exitError)
+	}
+	var code int
+	switch os.Args[1] {
+	case "validate":
+		code = runValidate(os.Args[2:])
+	case "check":
+		code = runCheck(os.Args[2:])
+	case "explain":
+		code = runExplain(os.Args[2:])
+	case "test":
+		code = runTest(os.Args[2:])
+	case "hash":
+		code = runHash(os.Args[2:])
+	case "diff":
+		code = runDiff(os.Args[2:])
+	case "schema":
+		code = runSchema(os.Args[2:])
+	case "migrate":
+		code = runMigrate(os.Args[2:])
+	case "list":
+		code = runList(os.Args[2:])
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "mapl: unknown command %q\n%v", os.Args[1], usage)
+		code = exitError
+	}
+	os.Exit(code)
+}
+
+// runValidate reads the rules files and prints the errors of each file. The exit code is the worst of the files
+// (files that cannot be read are reported and the other files are still validated).
+func runValidate(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return exitError
+	}
+	code := exitOK
+	for _, filename := range args {
+		rules, err := parseRulesFile(filename)
+		var pathErr *os.PathError
+		if errors.As(err, &pathErr) {
+			fmt.Fprintf(os.Stderr, "mapl: %v\n", err)
+			code = exitError
+			continue
+		}
+		if err != nil {
+			fmt.Printf("%v: %v\n", filename, err)
+			if code == exitOK {
+				code = exitFound
+			}
+			continue
+		}
+		fmt.Printf("%v: %v rules are valid\n", filename, len(rules.Rules))
+	}
+	return code
+}
+
+// messageResult is the result of the check of one message (the json output of the check command)
+type messageResult struct {
+	MessageIndex        int                       `json:"messageIndex"`
+	MessageID           string                    `json:"messageId,omitempty"`
+	Decision            int                       `json:"decision"`
+	DecisionString      string                    `json:"decisionString"`
+	RuleID              string                    `json:"ruleId,omitempty"` // the rule of the decision
+	RuleIndex           int                       `json:"ruleIndex"`
+	AppliedRulesIndices []int                     `json:"appliedRulesIndices"`
+	AuditResults        []MAPL_engine.AuditResult `json:"auditResults,omitempty"` // the audit rules that apply to the message
+}
+
+// runCheck checks the messages with the rules and prints the decisions
+func runCheck(args []string) int {
+	flags := flag.NewFlagSet("check", flag.ContinueOnError)
+	rulesFilename := flags.String("rules", "", "rules file (or a directory or manifest of a policy bundle)")
+	messagesFilename := flags.String("messages", "", "messages yaml file")
+	jsonOutput := flags.Bool("json", false, "print the decisions as json")
+	failOn := flags.String("fail-on", "", "exit with code 1 if a message has this decision or a more restrictive one (default, alert or block)")
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+	failDecision, ok := parseFailOn(*failOn)
+	if *rulesFilename == "" || *messagesFilename == "" || flags.NArg() != 0 || !ok {
+		fmt.Fprint(os.Stderr, usage)
+		return exitError
+	}
+	rules, messages, code := readRulesAndMessages(*rulesFilename, *messagesFilename)
+	if rules == nil {
+		return code
+	}
+
+	results := []messageResult{}
+	code = exitOK
+	for i := range messages.Messages {
+		decision, decisionString, ruleIndex, _, appliedRulesIndices, auditResults := MAPL_engine.CheckWithAudit(&messages.Messages[i], rules)
+		result := messageResult{
+			MessageIndex:        i,
+			MessageID:           messages.Messages[i].MessageID,
+			Decision:            decision,
+			DecisionString:      decisionString,
+			RuleIndex:           ruleIndex,
+			AppliedRulesIndices: appliedRulesIndices,
+			AuditResults:        auditResults,
+		}
+		if ruleIndex >= 0 {
+			result.RuleID = rules.Rules[ruleIndex].RuleID
+		}
+		results = append(results, result)
+		if failDecision >= 0 && failsOn(decision, failDecision) {
+			code = exitFound
+		}
+	}
+
+	if *jsonOutput {
+		printJson(results)
+		return code
+	}
+	for _, result := range results {
+		if result.RuleIndex >= 0 {
+			fmt.Printf("message #%v: decision=%v [%v] by rule #%v ; applicable rules =%v \n", result.MessageIndex, result.Decision, result.DecisionString, result.RuleID, result.AppliedRulesIndices)
+		} else {
+			fmt.Printf("message #%v: decision=%v [%v]\n", result.MessageIndex, result.Decision, result.DecisionString)
+		}
+		for _, auditResult := range result.AuditResults {
+			fmt.Printf("  audit: %v\n", auditResult)
+		}
+	}
+	return code
+}
+
+// parseFailOn returns the decision of the --fail-on flag (-1 if the flag is not used)
+func parseFailOn(str string) (int, bool) {
+	switch strings.ToLower(str) {
+	case "":
+		return -1, true
+	case "default":
+		return MAPL_engine.DEFAULT, true
+	case "alert":
+		return MAPL_engine.ALERT, true
+	case "block":
+		return MAPL_engine.BLOCK, true
+	}
+	return -1, false
+}
+
+// failsOn tests the decision with the decision of --fail-on. The default decision blocks the message so block and alert also fail on it.
+func failsOn(decision, failDecision int) bool {
+	if failDecision == MAPL_engine.DEFAULT {
+		return decision == MAPL_engine.DEFAULT
+	}
+	return decision >= failDecision || decision == MAPL_engine.DEFAULT
+}
+
+// runExplain prints the trace of the check of one message
+func runExplain(args []string) int {
+	flags := flag.NewFlagSet("explain", flag.ContinueOnError)
+	rulesFilename := flags.String("rules", "", "rules file (or a directory or manifest of a policy bundle)")
+	messagesFilename := flags.String("messages", "", "messages yaml file")
+	index := flags.Int("index", -1, "index of the message in the messages file")
+	messageID := flags.String("message-id", "", "message_id of the message in the messages file")
+	jsonOutput := flags.Bool("json", false, "print the trace as json")
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+	if *rulesFilename == "" || *messagesFilename == "" || flags.NArg() != 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return exitError
+	}
+	rules, messages, code := readRulesAndMessages(*rulesFilename, *messagesFilename)
+	if rules == nil {
+		return code
+	}
+
+	i, err := selectMessage(messages, *index, *messageID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "mapl: %v\n", err)
+		return exitError
+	}
+	_, _, _, _, _, trace := MAPL_engine.CheckWithTrace(&messages.Messages[i], rules)
+	if *jsonOutput {
+		fmt.Println(trace.ToJson())
+		return exitOK
+	}
+	printTrace(i, trace)
+	return exitOK
+}
+
+// selectMessage returns the index of the message selected by --index or --message-id. A messages file with one message does not need them.
+func selectMessage(messages *MAPL_engine.Messages, index int, messageID string) (int, error) {
+	switch {
+	case messageID != "":
+		for i, message := range messages.Messages {
+			if message.MessageID == messageID {
+				return i, nil
+			}
+		}
+		return -1, fmt.Errorf("no message with message_id %q", messageID)
+	case index >= 0:
+		if index >= len(messages.Messages) {
+			return -1, fmt.Errorf("no message with index %v (%v messages)", index, len(messages.Messages))
+		}
+		return index, nil
+	case len(messages.Messages) == 1:
+		return 0, nil
+	}
+	return -1, fmt.Errorf("%v messages: use --index or --message-id to select one", len(messages.Messages))
+}
+
+// printTrace prints the trace as text: the decision and then the stages and conditions of each rule
+func printTrace(messageIndex int, trace MAPL_engine.CheckTrace) {
+	fmt.Printf("message #%v: decision=%v [%v]", messageIndex, trace.Decision, trace.DecisionString)
+	if trace.RelevantRuleIndex >= 0 {
+		fmt.Printf(" by rule #%v", trace.Rules[trace.RelevantRuleIndex].RuleID)
+	}
+	fmt.Printf(" ; applicable rules =%v\n", trace.AppliedRulesIndices)
+	for _, auditResult := range trace.AuditResults {
+		fmt.Printf("audit: %v\n", auditResult)
+	}
+	for _, rule := range trace.Rules {
+		if rule.Audit {
+			fmt.Printf("rule #%v (audit): %v\n", rule.RuleID, rule.ResultString)
+		} else {
+			fmt.Printf("rule #%v: %v\n", rule.RuleID, rule.ResultString)
+		}
+		for _, stage := range rule.Stages {
+			if stage.NotEvaluated {
+				fmt.Printf("  %-13v not evaluated\n", stage.Stage)
+				continue
+			}
+			fmt.Printf("  %-13v %-5v message: %q rule: %q\n", stage.Stage, stage.Match, stage.MessageValue, stage.RulePattern)
+		}
+		for i, andConditions := range rule.DNFConditions {
+			fmt.Printf("  ANDconditions[%v]: %v\n", i, andConditions.Result)
+			for _, c := range andConditions.Conditions {
+				fmt.Printf("    <%v, %v, %v> %v message: %q\n", c.Attribute, c.Method, c.Value, c.Result, c.MessageValue)
+			}
+		}
+	}
+}
+
+// runTest compares the decisions of the messages with their expected_decision and expected_rule_id
+func runTest(args []string) int {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	rulesFilename := flags.String("rules", "", "rules file (or a directory or manifest of a policy bundle)")
+	messagesFilename := flags.String("messages", "", "messages yaml file")
+	jsonOutput := flags.Bool("json", false, "print the results as json")
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+	if *rulesFilename == "" || *messagesFilename == "" || flags.NArg() != 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return exitError
+	}
+	rules, messages, code := readRulesAndMessages(*rulesFilename, *messagesFilename)
+	if rules == nil {
+		return code
+	}
+
+	report := MAPL_engine.CheckExpectations(messages, rules)
+	if *jsonOutput {
+		printJson(report)
+	} else {
+		fmt.Print(report.String())
+	}
+	if report.Failed > 0 {
+		return exitFound
+	}
+	return exitOK
+}
+
+// runHash prints the md5 hash of each rule
+func runHash(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprint(os.Stderr, usage)
+		return exitError
+	}
+	rules, err := parseRulesFile(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "mapl: %v: %v\n", args[0], err)
+		return exitError
+	}
+	for i, rule := range rules.Rules {
+		fmt.Printf("rule #%v (rule_id %v): md5hash = %v\n", i, rule.RuleID, MAPL_engine.RuleMD5Hash(rule))
+	}
+	return exitOK
+}
+
+// listedRule is the metadata of one rule (the json output of the list command)
+type listedRule struct {
+	RuleIndex   int      `json:"ruleIndex"`
+	RuleID      string   `json:"ruleId,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Owner       string   `json:"owner,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	ValidFrom   string   `json:"validFrom,omitempty"`
+	ValidUntil  string   `json:"validUntil,omitempty"`
+	Decision    string   `json:"decision"`
+	Mode        string   `json:"mode,omitempty"`
+}
+
+// runList prints the metadata of the rules with the tag, of the owner and expiring within the number of days (all of the rules without flags)
+func runList(args []string) int {
+	flags := flag.NewFlagSet("list", flag.ContinueOnError)
+	tag := flags.String("tag", "", "list the rules with the tag")
+	owner := flags.String("owner", "", "list the rules of the owner")
+	expiringWithin := flags.Int("expiring-within", -1, "list the rules whose validity period ends within the number of days")
+	jsonOutput := flags.Bool("json", false, "print the rules as json")
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprint(os.Stderr, usage)
+		return exitError
+	}
+	rules, err := parseRulesFile(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "mapl: %v: %v\n", flags.Arg(0), err)
+		return exitError
+	}
+
+	selected := make([]int, len(rules.Rules)) // the number of filters that selected each rule
+	filters := 0
+	for _, filter := range []struct {
+		enabled bool
+		indices func() []int
+	}{
+		{*tag != "", func() []int { return MAPL_engine.RulesWithTag(rules, *tag) }},
+		{*owner != "", func() []int { return MAPL_engine.RulesOfOwner(rules, *owner) }},
+		{*expiringWithin >= 0, func() []int { return MAPL_engine.RulesExpiringWithin(rules, time.Now(), *expiringWithin) }},
+	} {
+		if !filter.enabled {
+			continue
+		}
+		filters++
+		for _, i := range filter.indices() {
+			selected[i]++
+		}
+	}
+
+	listed := []listedRule{}
+	for i, rule := range rules.Rules {
+		if selected[i] == filters {
+			listed = append(listed, listedRule{RuleIndex: i, RuleID: rule.RuleID, Description: rule.Description, Owner: rule.Owner, Tags: rule.Tags,
+				ValidFrom: rule.ValidFrom, ValidUntil: rule.ValidUntil, Decision: rule.Decision, Mode: rule.Mode})
+		}
+	}
+	if *jsonOutput {
+		printJson(listed)
+		return exitOK
+	}
+	for _, r := range listed {
+		line := fmt.Sprintf("rule #%v (rule_id %v): %v", r.RuleIndex, r.RuleID, r.Decision)
+		if r.Mode != "" {
+			line += " (" + strings.ToLower(r.Mode) + ")"
+		}
+		if r.Owner != "" {
+			line += ", owner " + r.Owner
+		}
+		if len(r.Tags) > 0 {
+			line += ", tags " + strings.Join(r.Tags, ",")
+		}
+		if r.ValidFrom != "" || r.ValidUntil != "" {
+			line += fmt.Sprintf(", valid %v..%v", r.ValidFrom, r.ValidUntil)
+		}
+		if r.Description != "" {
+			line += ": " + r.Description
+		}
+		fmt.Println(line)
+	}
+	return exitOK
+}
+
+// runDiff prints the semantic difference between two rules files
+func runDiff(args []string) int {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	jsonOutput := flags.Bool("json", false, "print the differences as json")
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+	if flags.NArg() != 2 {
+		fmt.Fprint(os.Stderr, usage)
+		return exitError
+	}
+
+	oldRules, err := parseRulesFile(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "mapl: %v: %v\n", flags.Arg(0), err)
+		return exitError
+	}
+	newRules, err := parseRulesFile(flags.Arg(1))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "mapl: %v: %v\n", flags.Arg(1), err)
+		return exitError
+	}
+
+	report := MAPL_analysis.Diff(oldRules, newRules)
+	if *jsonOutput {
+		fmt.Println(report.ToJson())
+	} else {
+		fmt.Print(report.String())
+	}
+	if len(report.Diffs) > 0 {
+		return exitFound
+	}
+	return exitOK
+}
+
+// runSchema prints (or writes to a file) the JSON Schema of the rules files or of the messages files
+func runSchema(args []string) int {
+	flags := flag.NewFlagSet("schema", flag.ContinueOnError)
+	output := flags.String("o", "", "write the schema to the file")
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprint(os.Stderr, usage)
+		return exitError
+	}
+
+	var schema []byte
+	var err error
+	switch flags.Arg(0) {
+	case "rules":
+		schema, err = MAPL_engine.RulesJSONSchema()
+	case "messages":
+		schema, err = MAPL_engine.MessagesJSONSchema()
+	default:
+		fmt.Fprintf(os.Stderr, "mapl: unknown schema %q (rules or messages)\n", flags.Arg(0))
+		return exitError
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "mapl: %v\n", err)
+		return exitError
+	}
+	schema = append(schema, '\n')
+
+	if *output == "" {
+		os.Stdout.Write(schema)
+		return exitOK
+	}
+	if err := ioutil.WriteFile(*output, schema, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "mapl: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+// runMigrate prints (or writes to a file) the rules of a version 1 rules file in the yaml format of the current version.
+// With -w the file is rewritten (files of the current version are not rewritten).
+func runMigrate(args []string) int {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	output := flags.String("o", "", "write the rules to the file")
+	write := flags.Bool("w", false, "rewrite the rules file")
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+	if flags.NArg() != 1 || (*write && *output != "") {
+		fmt.Fprint(os.Stderr, usage)
+		return exitError
+	}
+	filename := flags.Arg(0)
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "mapl: %v\n", err)
+		return exitError
+	}
+	version, err := MAPL_engine.RulesVersion(data)
+	if err != nil {
+		fmt.Printf("%v: %v\n", filename, err)
+		return exitFound
+	}
+	migrated, err := MAPL_engine.MigrateRules(data)
+	if err != nil {
+		fmt.Printf("%v: %v\n", filename, err)
+		return exitFound
+	}
+
+	switch {
+	case *write && version == MAPL_engine.CurrentRulesVersion:
+		fmt.Printf("%v: the rules are already of version %v\n", filename, version)
+		return exitOK
+	case *write:
+		*output = filename
+	case *output == "":
+		os.Stdout.Write(migrated)
+		return exitOK
+	}
+	if err := ioutil.WriteFile(*output, migrated, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "mapl: %v\n", err)
+		return exitError
+	}
+	fmt.Printf("%v: rules of version %v written to %v in version %v\n", filename, version, *output, MAPL_engine.CurrentRulesVersion)
+	return exitOK
+}
+
+// parseRulesFile reads a rules file, a directory or a manifest of a policy bundle with MAPL_engine.LoadBundle
+// (json for files with the .json extension and all of the documents of yaml files).
+func parseRulesFile(filename string) (*MAPL_engine.Rules, error) {
+	bundle, err := MAPL_engine.LoadBundle(filename)
+	if bundleErr, ok := err.(*MAPL_engine.BundleError); ok && bundleErr.File == filename {
+		return nil, bundleErr.Err // the file name is printed by the commands
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &bundle.Rules, nil
+}
+
+// readRulesAndMessages reads the rules and messages files. On errors it prints the error and returns nil rules and the exit code.
+func readRulesAndMessages(rulesFilename, messagesFilename string) (*MAPL_engine.Rules, *MAPL_engine.Messages, int) {
+	rules, err := parseRulesFile(rulesFilename)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "mapl: %v: %v\n", rulesFilename, err)
+		return nil, nil, exitError
+	}
+	messages, err := MAPL_engine.ParseMessagesFromFile(messagesFilename)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "mapl: %v: %v\n", messagesFilename, err)
+		return nil, nil, exitError
+	}
+	return rules, messages, exitOK
+}
+
+func printJson(v interface{}) {
+	jsonBytes, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		panic("error converting to json")
+	}
+	fmt.Println(string(jsonBytes))
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testRules = `rules:
+  - rule_id: get-books
+    sender:
+      senderName: "A.my_namespace"
+      senderType: service
+    receiver:
+      receiverName: "B.my_namespace"
+      receiverType: service
+    protocol: http
+    resource:
+      resourceType: httpPath
+      resourceName: "/books/*"
+    operation: GET
+    decision: allow
+  - rule_id: delete-books
+    sender:
+      senderName: "A.my_namespace"
+      senderType: service
+    receiver:
+      receiverName: "B.my_namespace"
+      receiverType: service
+    protocol: http
+    resource:
+      resourceType: httpPath
+      resourceName: "/books/*"
+    operation: DELETE
+    decision: alert
+`
+
+// the messages are GET (allow), DELETE (alert) and POST (the default decision) of A.my_namespace to B.my_namespace
+func testMessages(methods ...string) string {
+	expected := map[string]string{"GET": "allow", "DELETE": "alert", "POST": "default"}
+	messages := "messages:\n"
+	for _, method := range methods {
+		messages += `  - sender_service: A.my_namespace
+    receiver_service: B.my_namespace
+    request_protocol: http
+    request_path: /books/1
+    request_method: ` + method + `
+    expected_decision: ` + expected[method] + "\n"
+	}
+	return messages
+}
+
+// writeFiles writes the files to a temporary directory and returns the directory
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "mapl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// captureOutput runs the command and returns its exit code and the output to stdout and stderr
+func captureOutput(t *testing.T, run func(args []string) int, args ...string) (int, string) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = w, w
+	output := make(chan string)
+	go func() {
+		data, _ := ioutil.ReadAll(r)
+		output <- string(data)
+	}()
+	code := run(args)
+	os.Stdout, os.Stderr = stdout, stderr
+	w.Close()
+	return code, <-output
+}
+
+func TestValidate(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"rules.yaml":     testRules,
+		"invalid.yaml":   strings.Replace(testRules, "senderType: service", "senderType: pod", 1),
+		"documents.yaml": testRules + "---\n" + strings.Replace(testRules, "rule_id: ", "rule_id: second-", -1),
+	})
+	defer os.RemoveAll(dir)
+	rules := filepath.Join(dir, "rules.yaml")
+	invalid := filepath.Join(dir, "invalid.yaml")
+	documents := filepath.Join(dir, "documents.yaml")
+	missing := filepath.Join(dir, "missing.yaml")
+
+	tests := []struct {
+		args   []string
+		code   int
+		output []string
+	}{
+		{[]string{}, exitError, nil},
+		{[]string{rules}, exitOK, []string{rules + ": 2 rules are valid"}},
+		{[]string{documents}, exitOK, []string{documents + ": 4 rules are valid"}},
+		{[]string{rules, invalid}, exitFound, []string{rules + ": 2 rules are valid", invalid + ": rules[0].sender.senderType"}},
+		{[]string{missing}, exitError, []string{missing}},
+		// all of the files are reported after a file that cannot be read
+		{[]string{missing, rules, invalid}, exitError, []string{missing, rules + ": 2 rules are valid", invalid + ": rules[0].sender.senderType"}},
+		{[]string{invalid, missing}, exitError, []string{invalid + ": rules[0].sender.senderType", missing}},
+	}
+	for _, test := range tests {
+		code, output := captureOutput(t, runValidate, test.args...)
+		if code != test.code {
+			t.Errorf("validate %v: expected exit code %v, got %v: %v", test.args, test.code, code, output)
+		}
+		for _, str := range test.output {
+			if !strings.Contains(output, str) {
+				t.Errorf("validate %v: %q is not in the output %v", test.args, str, output)
+			}
+		}
+	}
+}
+
+func TestCheckFailOn(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"rules.yaml":    testRules,
+		"allowed.yaml":  testMessages("GET"),
+		"alert.yaml":    testMessages("GET", "DELETE"),
+		"default.yaml":  testMessages("GET", "POST"),
+		"invalid.yaml":  "messages:\n  - request_time: yesterday\n",
+		"invalid2.yaml": "rules: [",
+	})
+	defer os.RemoveAll(dir)
+	rules := filepath.Join(dir, "rules.yaml")
+
+	tests := []struct {
+		messages, failOn string
+		code             int
+	}{
+		{"allowed.yaml", "", exitOK},
+		{"allowed.yaml", "default", exitOK},
+		{"allowed.yaml", "block", exitOK},
+		{"alert.yaml", "", exitOK},
+		{"alert.yaml", "block", exitOK},
+		{"alert.yaml", "alert", exitFound},
+		{"alert.yaml", "default", exitOK},
+		{"default.yaml", "", exitOK},
+		{"default.yaml", "default", exitFound},
+		{"default.yaml", "block", exitFound}, // the default decision blocks the message
+		{"default.yaml", "alert", exitFound},
+		{"alert.yaml", "allow", exitError},
+		{"invalid.yaml", "", exitError},
+		{"missing.yaml", "block", exitError},
+	}
+	for _, test := range tests {
+		args := []string{"--rules", rules, "--messages", filepath.Join(dir, test.messages)}
+		if test.failOn != "" {
+			args = append(args, "--fail-on", test.failOn)
+		}
+		if code, output := captureOutput(t, runCheck, args...); code != test.code {
+			t.Errorf("check %v --fail-on %v: expected exit code %v, got %v: %v", test.messages, test.failOn, test.code, code, output)
+		}
+	}
+
+	if code, output := captureOutput(t, runCheck, "--rules", filepath.Join(dir, "invalid2.yaml"), "--messages", filepath.Join(dir, "allowed.yaml")); code != exitError {
+		t.Errorf("check with invalid rules: expected exit code %v, got %v: %v", exitError, code, output)
+	}
+}
+
+func TestTest(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"rules.yaml":  testRules,
+		"passed.yaml": testMessages("GET", "DELETE", "POST"),
+		"failed.yaml": strings.Replace(testMessages("GET", "DELETE"), "expected_decision: alert", "expected_decision: allow", 1),
+	})
+	defer os.RemoveAll(dir)
+	rules := filepath.Join(dir, "rules.yaml")
+
+	tests := []struct {
+		args []string
+		code int
+	}{
+		{[]string{"--rules", rules, "--messages", filepath.Join(dir, "passed.yaml")}, exitOK},
+		{[]string{"--rules", rules, "--messages", filepath.Join(dir, "failed.yaml")}, exitFound},
+		{[]string{"--rules", rules, "--messages", filepath.Join(dir, "failed.yaml"), "--json"}, exitFound},
+		{[]string{"--rules", filepath.Join(dir, "missing.yaml"), "--messages", filepath.Join(dir, "passed.yaml")}, exitError},
+		{[]string{"--rules", rules}, exitError},
+	}
+	for _, test := range tests {
+		if code, output := captureOutput(t, runTest, test.args...); code != test.code {
+			t.Errorf("test %v: expected exit code %v, got %v: %v", test.args, test.code, code, output)
+		}
+	}
+}
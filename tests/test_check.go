@@ -52,6 +52,11 @@ func main() {
 	Test_CheckMessages("examples/rules_sender_list.yaml","examples/messages_sender_test_with_lists.yaml")
 	fmt.Println("----------------------")
 
+	str="test whitelist: receiver lists. Expected results: messages 0,1: allow, messages 2: block by default (no relevant whitelist entry)"
+	fmt.Println(str)
+	Test_CheckMessages("examples/rules_receiver_list.yaml","examples/messages_receiver_test_with_lists.yaml")
+	fmt.Println("----------------------")
+
 	str="est whitelist: resources with wildcards. Expected results: message 0: alert, message 1: block , message 2: block by default (no relevant whitelist entry)"
 	fmt.Println(str)
 	Test_CheckMessages("examples/rules_resources.yaml","examples/messages_resources.yaml")
@@ -89,6 +94,89 @@ func main() {
 	Test_CheckMessages("examples/rules_with_conditions.yaml","examples/messages_test_with_conditions.yaml")
 	fmt.Println("----------------------")
 
+	// test existence of attributes. the rules with even index test EX and the rules with odd index test NEX. Expected results:
+	// message 0: alert. applicable rules: 0,2,4,6,8,10 (all of the attributes exist)
+	// message 1: alert. applicable rules: 1,3,5,7,9,11 (none of the attributes exist)
+	// message 2: alert. applicable rules: 0,3,5,7,9,11 (only payloadSize exists)
+	str="test existence of attributes (EX and NEX). message 0: alert by rules 0,2,4,6,8,10, message 1: alert by rules 1,3,5,7,9,11, message 2: alert by rules 0,3,5,7,9,11"
+	fmt.Println(str)
+	Test_CheckMessages("examples/rules_existence.yaml","examples/messages_existence.yaml")
+	fmt.Println("----------------------")
+
+	// test time conditions (time zones, days of week, dates and cron expressions). Expected results:
+	// message 0: allow by rule 0, message 1: block by rule 1, message 2: block by rule 1,
+	// message 3: allow by rule 2, message 4: block by rule 3, message 5: alert by rule 4, message 6: default (no rule)
+	str="test time conditions. message 0: allow, message 1: block, message 2: block, message 3: allow, message 4: block, message 5: alert, message 6: default"
+	fmt.Println(str)
+	Test_CheckMessages("examples/rules_time_windows.yaml","examples/messages_time_windows.yaml")
+	fmt.Println("----------------------")
+
+	// test CIDR conditions on the sender and receiver ips. Expected results:
+	// message 0: allow by rule 0, message 1: block by rule 1, message 2: allow by rule 0, message 3: block by default (no sender ip, the CIDR conditions are false), message 4: alert by rule 2
+	str="test CIDR conditions. message 0: allow, message 1: block, message 2: allow, message 3: block by default, message 4: alert"
+	fmt.Println(str)
+	Test_CheckMessages("examples/rules_cidr_conditions.yaml","examples/messages_cidr_conditions.yaml")
+	fmt.Println("----------------------")
+
+	// test the IN, NIN, BETWEEN and NBETWEEN methods. Expected results:
+	// message 0: alert. applicable rules: 0,1,2,3,4,5,6
+	// message 1: default (no rule)
+	// message 2: alert. applicable rules: 0,1,4,6
+	str="test sets and ranges (IN, NIN, BETWEEN, NBETWEEN). message 0: alert by rules 0,1,2,3,4,5,6, message 1: default, message 2: alert by rules 0,1,4,6"
+	fmt.Println(str)
+	Test_CheckMessages("examples/rules_sets_and_ranges.yaml","examples/messages_sets_and_ranges.yaml")
+	fmt.Println("----------------------")
+
+	// test kafka topics and consumer groups. Expected results:
+	// message 0: allow by rule 0, message 1: allow by rule 1, message 2: allow by rule 2, message 3: block by rule 3, message 4: default (no rule), message 5: alert by rule 4
+	str="test kafka rules. message 0: allow, message 1: allow, message 2: allow, message 3: block, message 4: default, message 5: alert"
+	fmt.Println(str)
+	Test_CheckMessages("examples/rules_kafka.yaml","examples/messages_kafka.yaml")
+	fmt.Println("----------------------")
+
+	// test gRPC services and methods. Expected results:
+	// message 0: allow by rule 0, message 1: allow by rule 1, message 2: default (no rule), message 3: block by rule 2,
+	// message 4: alert by rule 3 (applicable rules 0,3), message 5: alert by rule 3 (applicable rules 1,3)
+	str="test gRPC rules. message 0: allow, message 1: allow, message 2: default, message 3: block by default, message 4: alert, message 5: alert"
+	fmt.Println(str)
+	Test_CheckMessages("examples/rules_grpc.yaml","examples/messages_grpc.yaml")
+	fmt.Println("----------------------")
+
+	// test lists and wildcards in the protocol and resource type. Expected results:
+	// message 0: allow by rule 0, message 1: allow by rule 0, message 2: alert by rule 1, message 3: default (no rule), message 4: block by rule 2
+	str="test protocol and resource type patterns. message 0: allow, message 1: allow, message 2: alert, message 3: default, message 4: block"
+	fmt.Println(str)
+	Test_CheckMessages("examples/rules_protocol_patterns.yaml","examples/messages_protocol_patterns.yaml")
+	fmt.Println("----------------------")
+
+	// test the namespace, principal and labels sender/receiver types. Expected results:
+	// message 0: allow by rule 0, message 1: allow by rule 1, message 2: default (no rule), message 3: allow by rule 2, message 4: default (no rule), message 5: alert by rule 3
+	str="test sender/receiver types. message 0: allow, message 1: allow, message 2: default, message 3: allow, message 4: default, message 5: alert"
+	fmt.Println(str)
+	Test_CheckMessages("examples/rules_sender_receiver_types.yaml","examples/messages_sender_receiver_types.yaml")
+	fmt.Println("----------------------")
+
+	// test the validity period of rules. Expected results:
+	// message 0: allow by rule 1, message 1: allow by rule 1, message 2: default (no valid rule), message 3: alert by rule 2, message 4: default (no valid rule), message 5: allow by rule 0
+	str="test rule validity periods. message 0: allow, message 1: allow, message 2: default, message 3: alert, message 4: default, message 5: allow"
+	fmt.Println(str)
+	Test_CheckMessages("examples/rules_metadata.yaml","examples/messages_metadata.yaml")
+	fmt.Println("----------------------")
+
+	// test rules in audit mode (their decisions are shown but do not change the decisions). Expected results:
+	// message 0: allow by rule 0, message 1: allow by rule 0 (audit: block by rule 2), message 2: allow by rule 1 (audit: alert by rule 3), message 3: default (audit: block by rule 2)
+	str="test audit rules. message 0: allow, message 1: allow, message 2: allow, message 3: default"
+	fmt.Println(str)
+	Test_CheckMessages("examples/rules_audit.yaml","examples/messages_audit.yaml")
+	fmt.Println("----------------------")
+
+	// test label selectors in conditions. Expected results:
+	// message 0: allow by rule 0, message 1: default (no rule), message 2: default (no rule), message 3: block by rule 1, message 4: block by rule 1 (applicable rules 1,2)
+	str="test label selector conditions. message 0: allow, message 1: default, message 2: default, message 3: block, message 4: block"
+	fmt.Println(str)
+	Test_CheckMessages("examples/rules_label_selectors.yaml","examples/messages_label_selectors.yaml")
+	fmt.Println("----------------------")
+
 	//-------------------------------------------------------------------------------------------------------------------------------------------------
 	str="test rules for istio's bookinfo app"
 	fmt.Println(str)
@@ -108,14 +196,25 @@ func Test_CheckMessages(rulesFilename string,messagesFilename string) {
 
 	for i_message, message := range(messages.Messages) {
 
-		result, msg, relevantRuleIndex, _ , appliedRulesIndices := MAPL_engine.Check(&message, &rules)
+		result, msg, relevantRuleIndex, _ , appliedRulesIndices, auditResults := MAPL_engine.CheckWithAudit(&message, &rules)
 		if relevantRuleIndex>=0 {
 			fmt.Printf("message #%v: decision=%v [%v] by rule #%v ; applicable rules =%v \n", i_message, result, msg, rules.Rules[relevantRuleIndex].RuleID,appliedRulesIndices)
 		} else {
 			fmt.Printf("message #%v: decision=%v [%v]\n", i_message, result, msg)
 		}
+		for _, auditResult := range auditResults {
+			fmt.Printf("  audit: %v\n", auditResult)
+		}
 
 	}
+
+	// compare with the expected_decision and expected_rule_id of the messages (only the failures are shown)
+	report := MAPL_engine.CheckExpectations(&messages, &rules)
+	for _, result := range report.Results {
+		if !result.Pass {
+			fmt.Printf("FAIL message #%v: %v\n", result.MessageIndex, result.Diff)
+		}
+	}
 }
 
 